@@ -7,7 +7,6 @@ package cache
 
 import (
 	"github.com/bradfitz/gomemcache/memcache"
-	json "github.com/json-iterator/go"
 	"github.com/vmihailenco/msgpack"
 	"reflect"
 )
@@ -30,13 +29,9 @@ func NewMemcahce(server []string) *Memcache {
 }
 
 func (m *Memcache) Get(key string) interface{} {
+	// cache miss or server error are both treated as miss.
 	item, err := m.gomc.Get(key)
 	if err != nil {
-		if err == memcache.ErrCacheMiss {
-			return nil
-		} else {
-			panic(err)
-		}
 		return nil
 	}
 
@@ -46,16 +41,16 @@ func (m *Memcache) Get(key string) interface{} {
 	var v interface{}
 
 
-	if err := msgpack.Unmarshal(item.Value, v); err != nil {
+	if err := msgpack.Unmarshal(item.Value, &v); err != nil {
 		return nil
 	}
 
 	//  convert to []map[string]interface from []interface
 	if item.Flags == flagArray {
 		//fmt.Printf("%x \n",v)
-		sliceObj := v.([]interface{})
+		sliceObj, ok := v.([]interface{})
 		//check type for map[string]interface
-		if reflect.TypeOf(sliceObj[0]) == reflect.TypeOf(map[string]interface{}{}) {
+		if ok && len(sliceObj) > 0 && reflect.TypeOf(sliceObj[0]) == reflect.TypeOf(map[string]interface{}{}) {
 			result := make([]map[string]interface{}, len(sliceObj))
 			for i := 0; i < len(sliceObj); i++ {
 				result[i] = sliceObj[i].(map[string]interface{})
@@ -84,7 +79,7 @@ func (m *Memcache) Set(key string, value interface{}) bool {
 }
 
 func (m *Memcache) SetWithExpire(key string, value interface{}, expire int32) bool {
-	b, err := json.Marshal(value)
+	b, err := msgpack.Marshal(value)
 	if err != nil {
 		return false
 	}
@@ -11,6 +11,7 @@ type DBConfig struct {
 	MaxActive   int //Max active connections.
 	MaxIdle     int //Max Idle connections.
 	IdleTimeout int //Second
+	Weight      int //Weight of reader for balancer.Default 1
 }
 
 //Database connect config
@@ -0,0 +1,59 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-25
+ */
+package dsl
+
+import "strings"
+
+// DeleteBuilder builds DELETE statement.
+// eg: Delete("users").Where("id = ?", 1)
+type DeleteBuilder struct {
+	clauses
+	table string
+}
+
+func Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{table: table}
+}
+
+// add the condition joined by AND.The slice arg is expanded,eg: Where("id IN (?)", ids)
+func (b *DeleteBuilder) Where(cond string, args ...interface{}) *DeleteBuilder {
+	b.where = append(b.where, expr{cond, args})
+	return b
+}
+
+func (b *DeleteBuilder) OrderBy(orders ...string) *DeleteBuilder {
+	b.orderBy = append(b.orderBy, orders...)
+	return b
+}
+
+func (b *DeleteBuilder) Limit(limit int64) *DeleteBuilder {
+	b.limit = limit
+	return b
+}
+
+// The where condition is required to avoid deleting all rows by mistake.
+func (b *DeleteBuilder) Build() (string, []interface{}, error) {
+	if b.table == "" {
+		return "", nil, ERR_NOTABLE
+	}
+	if len(b.where) == 0 {
+		return "", nil, ERR_NOCONDITION
+	}
+	var (
+		buf  strings.Builder
+		args []interface{}
+	)
+	buf.WriteString("DELETE FROM " + b.table)
+	if err := b.clauses.write(&buf, &args); err != nil {
+		return "", nil, err
+	}
+	return buf.String(), args, nil
+}
+
+// the final SQL with the args interpolated.
+func (b *DeleteBuilder) String() string {
+	return toString(b)
+}
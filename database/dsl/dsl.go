@@ -0,0 +1,126 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-25
+ */
+package dsl
+
+import (
+	"fmt"
+	"github.com/AbelZhou/even/database/sql"
+	"strconv"
+	"strings"
+)
+
+// an SQL fragment with args,eg: "id IN (?)" with []int64{1,2}.
+type expr struct {
+	sql  string
+	args []interface{}
+}
+
+// the clauses shared by SELECT,UPDATE and DELETE.
+type clauses struct {
+	where   []expr
+	orderBy []string
+	limit   int64
+	offset  int64
+}
+
+// write the fragment and expand the slice args.
+func (e expr) write(buf *strings.Builder, args *[]interface{}) error {
+	var (
+		argIdx  int
+		quote   rune
+		escaped bool
+	)
+	for _, r := range e.sql {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			// the backslash escapes the next character in string literals
+			if r == '\\' && quote != '`' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			if argIdx >= len(e.args) {
+				return fmt.Errorf("the args of %q are less than placeholders", e.sql)
+			}
+			arg := e.args[argIdx]
+			argIdx++
+			if values, ok := sql.ExpandSlice(arg); ok {
+				if len(values) == 0 {
+					return fmt.Errorf("the slice arg of %q is empty", e.sql)
+				}
+				buf.WriteString(strings.Repeat("?, ", len(values)-1) + "?")
+				*args = append(*args, values...)
+				continue
+			}
+			*args = append(*args, arg)
+		}
+		buf.WriteRune(r)
+	}
+	if argIdx != len(e.args) {
+		return fmt.Errorf("the args of %q are more than placeholders", e.sql)
+	}
+	return nil
+}
+
+// write the conditions joined by AND.
+// Every condition is parenthesized when there are more than one conditions,
+// so the condition with OR or XOR keeps its precedence.
+func writeConditions(buf *strings.Builder, args *[]interface{}, keyword string, conds []expr) error {
+	if len(conds) == 0 {
+		return nil
+	}
+	buf.WriteString(" " + keyword + " ")
+	for i, cond := range conds {
+		if i > 0 {
+			buf.WriteString(" AND ")
+		}
+		paren := len(conds) > 1
+		if paren {
+			buf.WriteString("(")
+		}
+		if err := cond.write(buf, args); err != nil {
+			return err
+		}
+		if paren {
+			buf.WriteString(")")
+		}
+	}
+	return nil
+}
+
+// write WHERE,ORDER BY and LIMIT.
+func (c *clauses) write(buf *strings.Builder, args *[]interface{}) error {
+	if err := writeConditions(buf, args, "WHERE", c.where); err != nil {
+		return err
+	}
+	if len(c.orderBy) > 0 {
+		buf.WriteString(" ORDER BY " + strings.Join(c.orderBy, ", "))
+	}
+	if c.offset > 0 && c.limit <= 0 {
+		return ERR_OFFSETWITHOUTLIMIT
+	}
+	if c.limit > 0 {
+		buf.WriteString(" LIMIT " + strconv.FormatInt(c.limit, 10))
+		if c.offset > 0 {
+			buf.WriteString(" OFFSET " + strconv.FormatInt(c.offset, 10))
+		}
+	}
+	return nil
+}
+
+// the final SQL for review.
+func toString(stmt sql.Statement) string {
+	query, args, err := stmt.Build()
+	if err != nil {
+		return "ERROR: " + err.Error()
+	}
+	return sql.Interpolate(query, args)
+}
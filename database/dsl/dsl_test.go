@@ -0,0 +1,98 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-25
+ */
+package dsl
+
+import (
+	"github.com/AbelZhou/even/database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	q := Select("u.id", "COUNT(*) AS orders").From("users u").
+		LeftJoin("orders o ON o.user_id = u.id AND o.status = ?", 1).
+		Where("u.id IN (?)", []int64{1, 2}).Where("u.nickname = ? OR u.mobile = ?", "abel", "18600000000").
+		GroupBy("u.id").Having("COUNT(*) > ?", 2).OrderBy("u.id DESC").Limit(10).Offset(20)
+	query, args, err := q.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "SELECT u.id, COUNT(*) AS orders FROM users u LEFT JOIN orders o ON o.user_id = u.id AND o.status = ?" +
+		" WHERE (u.id IN (?, ?)) AND (u.nickname = ? OR u.mobile = ?) GROUP BY u.id HAVING COUNT(*) > ?" +
+		" ORDER BY u.id DESC LIMIT 10 OFFSET 20"
+	if query != expect {
+		t.Errorf("Select mismatch.got:%s", query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, int64(1), int64(2), "abel", "18600000000", 2}) {
+		t.Errorf("Select args mismatch.got:%v", args)
+	}
+
+	if s := Select().From("users").Where("nickname = ?", "a'b").String(); s != "SELECT * FROM users WHERE nickname = 'a\\'b'" {
+		t.Errorf("String mismatch.got:%s", s)
+	}
+	// NOT a OR b AND c must not become NOT a OR (b AND c)
+	if query, _, _ = Select().From("users").Where("NOT deleted OR admin = ?", 1).Where("status = ?", 1).Build(); query != "SELECT * FROM users WHERE (NOT deleted OR admin = ?) AND (status = ?)" {
+		t.Errorf("Conditions must be parenthesized.got:%s", query)
+	}
+	if _, _, err = Select().From("users").Offset(20).Build(); err != ERR_OFFSETWITHOUTLIMIT {
+		t.Errorf("Offset without limit must return an error.got:%v", err)
+	}
+	if _, _, err = Select().From("users").Where("id = ?").Build(); err == nil {
+		t.Error("The missing args must return an error.")
+	}
+	if _, _, err = Select().From("users").Where("id IN (?)", []int{}).Build(); err == nil {
+		t.Error("The empty slice must return an error.")
+	}
+	query, _, err = Select().From("users").Where(`nickname = 'it\'s ?' AND id IN (?)`, []int{1, 2}).Build()
+	if query != `SELECT * FROM users WHERE nickname = 'it\'s ?' AND id IN (?, ?)` || err != nil {
+		t.Errorf("The escaped quote must not end the string.got:%s %v", query, err)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	query, args, err := Insert("users").Ignore().Columns("mobile", "nickname").
+		Values("18600000000", "a").Values("18600000001", "b").
+		OnDuplicateKeyUpdate("nickname = VALUES(nickname)").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "INSERT IGNORE INTO users (mobile, nickname) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE nickname = VALUES(nickname)" ||
+		len(args) != 4 {
+		t.Errorf("Insert mismatch.got:%s %v", query, args)
+	}
+	if _, _, err = Insert("users").Values("1").Build(); err != sql.ERR_NOCOLUMNS {
+		t.Errorf("The missing columns must return an error.got:%v", err)
+	}
+	if _, _, err = Insert("users").Columns("mobile").Values("1", "2").Build(); err == nil {
+		t.Error("The mismatched values must return an error.")
+	}
+}
+
+func TestUpdateDelete(t *testing.T) {
+	query, args, err := Update("users").Set("nickname", "abel").SetExpr("version = version + ?", 1).
+		Where("id = ?", 10).Limit(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "UPDATE users SET nickname = ?, version = version + ? WHERE id = ? LIMIT 1" ||
+		!reflect.DeepEqual(args, []interface{}{"abel", 1, 10}) {
+		t.Errorf("Update mismatch.got:%s %v", query, args)
+	}
+	if _, _, err = Update("users").Set("nickname", "abel").Build(); err != ERR_NOCONDITION {
+		t.Errorf("Update without condition must return an error.got:%v", err)
+	}
+
+	query, args, err = Delete("users").Where("id IN (?)", []int{1, 2}).OrderBy("id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "DELETE FROM users WHERE id IN (?, ?) ORDER BY id" || len(args) != 2 {
+		t.Errorf("Delete mismatch.got:%s %v", query, args)
+	}
+	if _, _, err = Delete("users").Build(); err != ERR_NOCONDITION {
+		t.Errorf("Delete without condition must return an error.got:%v", err)
+	}
+}
@@ -0,0 +1,16 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-25
+ */
+package dsl
+
+import "errors"
+
+var ERR_NOTABLE = errors.New("The table is required.")
+
+var ERR_NOVALUES = errors.New("The values are required.")
+
+var ERR_NOCONDITION = errors.New("The where condition is required.Use Where(\"1 = 1\") to affect all rows.")
+
+var ERR_OFFSETWITHOUTLIMIT = errors.New("The offset requires the limit.")
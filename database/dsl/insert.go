@@ -0,0 +1,97 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-25
+ */
+package dsl
+
+import (
+	"fmt"
+	"github.com/AbelZhou/even/database/sql"
+	"strings"
+)
+
+// InsertBuilder builds INSERT statement.
+// eg: Insert("users").Columns("mobile", "nickname").Values("18600000000", "abel")
+type InsertBuilder struct {
+	ignore  bool
+	table   string
+	columns []string
+	rows    [][]interface{}
+	updates []expr
+}
+
+func Insert(table string) *InsertBuilder {
+	return &InsertBuilder{table: table}
+}
+
+// INSERT IGNORE.
+func (b *InsertBuilder) Ignore() *InsertBuilder {
+	b.ignore = true
+	return b
+}
+
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// add a row,it's called for every row of multiple rows.
+func (b *InsertBuilder) Values(values ...interface{}) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// eg: OnDuplicateKeyUpdate("nickname = VALUES(nickname)")
+func (b *InsertBuilder) OnDuplicateKeyUpdate(assignment string, args ...interface{}) *InsertBuilder {
+	b.updates = append(b.updates, expr{assignment, args})
+	return b
+}
+
+func (b *InsertBuilder) Build() (string, []interface{}, error) {
+	if b.table == "" {
+		return "", nil, ERR_NOTABLE
+	}
+	if len(b.columns) == 0 {
+		return "", nil, sql.ERR_NOCOLUMNS
+	}
+	if len(b.rows) == 0 {
+		return "", nil, ERR_NOVALUES
+	}
+	var (
+		buf  strings.Builder
+		args []interface{}
+	)
+	buf.WriteString("INSERT ")
+	if b.ignore {
+		buf.WriteString("IGNORE ")
+	}
+	buf.WriteString("INTO " + b.table + " (" + strings.Join(b.columns, ", ") + ") VALUES ")
+	placeholders := "(" + strings.Repeat("?, ", len(b.columns)-1) + "?)"
+	for i, row := range b.rows {
+		if len(row) != len(b.columns) {
+			return "", nil, fmt.Errorf("the values of row %d are %d,but columns are %d", i, len(row), len(b.columns))
+		}
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(placeholders)
+		args = append(args, row...)
+	}
+	for i, update := range b.updates {
+		if i == 0 {
+			buf.WriteString(" ON DUPLICATE KEY UPDATE ")
+		} else {
+			buf.WriteString(", ")
+		}
+		if err := update.write(&buf, &args); err != nil {
+			return "", nil, err
+		}
+	}
+	return buf.String(), args, nil
+}
+
+// the final SQL with the args interpolated.
+func (b *InsertBuilder) String() string {
+	return toString(b)
+}
@@ -0,0 +1,138 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-25
+ */
+package dsl
+
+import "strings"
+
+// SelectBuilder builds SELECT statement.
+// eg: Select("id", "nickname").From("users").Where("id IN (?)", ids).OrderBy("id DESC").Limit(10)
+type SelectBuilder struct {
+	clauses
+	distinct  bool
+	columns   []string
+	from      string
+	joins     []expr
+	groupBy   []string
+	having    []expr
+	forUpdate bool
+}
+
+// select columns,all the columns(*) are selected when columns is empty.
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+func (b *SelectBuilder) Distinct() *SelectBuilder {
+	b.distinct = true
+	return b
+}
+
+// table or "table AS alias".
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.from = table
+	return b
+}
+
+// eg: Join("orders o ON o.user_id = u.id")
+func (b *SelectBuilder) Join(join string, args ...interface{}) *SelectBuilder {
+	b.joins = append(b.joins, expr{"JOIN " + join, args})
+	return b
+}
+
+func (b *SelectBuilder) LeftJoin(join string, args ...interface{}) *SelectBuilder {
+	b.joins = append(b.joins, expr{"LEFT JOIN " + join, args})
+	return b
+}
+
+// add the condition joined by AND.The slice arg is expanded,eg: Where("id IN (?)", ids)
+func (b *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	b.where = append(b.where, expr{cond, args})
+	return b
+}
+
+func (b *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	b.groupBy = append(b.groupBy, columns...)
+	return b
+}
+
+// add the HAVING condition joined by AND.
+func (b *SelectBuilder) Having(cond string, args ...interface{}) *SelectBuilder {
+	b.having = append(b.having, expr{cond, args})
+	return b
+}
+
+// eg: OrderBy("create_time DESC", "id")
+func (b *SelectBuilder) OrderBy(orders ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, orders...)
+	return b
+}
+
+func (b *SelectBuilder) Limit(limit int64) *SelectBuilder {
+	b.limit = limit
+	return b
+}
+
+// Offset works with Limit,Build returns ERR_OFFSETWITHOUTLIMIT without Limit.
+func (b *SelectBuilder) Offset(offset int64) *SelectBuilder {
+	b.offset = offset
+	return b
+}
+
+// lock the selected rows in transaction.
+func (b *SelectBuilder) ForUpdate() *SelectBuilder {
+	b.forUpdate = true
+	return b
+}
+
+func (b *SelectBuilder) Build() (string, []interface{}, error) {
+	if b.from == "" {
+		return "", nil, ERR_NOTABLE
+	}
+	var (
+		buf  strings.Builder
+		args []interface{}
+	)
+	buf.WriteString("SELECT ")
+	if b.distinct {
+		buf.WriteString("DISTINCT ")
+	}
+	if len(b.columns) == 0 {
+		buf.WriteString("*")
+	} else {
+		buf.WriteString(strings.Join(b.columns, ", "))
+	}
+	buf.WriteString(" FROM " + b.from)
+	for _, join := range b.joins {
+		buf.WriteString(" ")
+		if err := join.write(&buf, &args); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := writeConditions(&buf, &args, "WHERE", b.where); err != nil {
+		return "", nil, err
+	}
+	if len(b.groupBy) > 0 {
+		buf.WriteString(" GROUP BY " + strings.Join(b.groupBy, ", "))
+	}
+	if err := writeConditions(&buf, &args, "HAVING", b.having); err != nil {
+		return "", nil, err
+	}
+	// the where conditions have been written
+	tail := b.clauses
+	tail.where = nil
+	if err := tail.write(&buf, &args); err != nil {
+		return "", nil, err
+	}
+	if b.forUpdate {
+		buf.WriteString(" FOR UPDATE")
+	}
+	return buf.String(), args, nil
+}
+
+// the final SQL with the args interpolated.
+func (b *SelectBuilder) String() string {
+	return toString(b)
+}
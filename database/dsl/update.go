@@ -0,0 +1,83 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-25
+ */
+package dsl
+
+import "strings"
+
+// UpdateBuilder builds UPDATE statement.
+// eg: Update("users").Set("nickname", "abel").SetExpr("version = version + ?", 1).Where("id = ?", 1)
+type UpdateBuilder struct {
+	clauses
+	table string
+	sets  []expr
+}
+
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+// set column to value.
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	b.sets = append(b.sets, expr{column + " = ?", []interface{}{value}})
+	return b
+}
+
+// set by an expression,eg: SetExpr("count = count + ?", 1)
+func (b *UpdateBuilder) SetExpr(assignment string, args ...interface{}) *UpdateBuilder {
+	b.sets = append(b.sets, expr{assignment, args})
+	return b
+}
+
+// add the condition joined by AND.The slice arg is expanded,eg: Where("id IN (?)", ids)
+func (b *UpdateBuilder) Where(cond string, args ...interface{}) *UpdateBuilder {
+	b.where = append(b.where, expr{cond, args})
+	return b
+}
+
+func (b *UpdateBuilder) OrderBy(orders ...string) *UpdateBuilder {
+	b.orderBy = append(b.orderBy, orders...)
+	return b
+}
+
+func (b *UpdateBuilder) Limit(limit int64) *UpdateBuilder {
+	b.limit = limit
+	return b
+}
+
+// The where condition is required to avoid updating all rows by mistake.
+func (b *UpdateBuilder) Build() (string, []interface{}, error) {
+	if b.table == "" {
+		return "", nil, ERR_NOTABLE
+	}
+	if len(b.sets) == 0 {
+		return "", nil, ERR_NOVALUES
+	}
+	if len(b.where) == 0 {
+		return "", nil, ERR_NOCONDITION
+	}
+	var (
+		buf  strings.Builder
+		args []interface{}
+	)
+	buf.WriteString("UPDATE " + b.table + " SET ")
+	for i, set := range b.sets {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if err := set.write(&buf, &args); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := b.clauses.write(&buf, &args); err != nil {
+		return "", nil, err
+	}
+	return buf.String(), args, nil
+}
+
+// the final SQL with the args interpolated.
+func (b *UpdateBuilder) String() string {
+	return toString(b)
+}
@@ -0,0 +1,120 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-03
+ */
+package sql
+
+import (
+	"database/sql"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+)
+
+// Replica is a healthy reader offered to Balancer.
+type Replica struct {
+	Index  int //index of the reader in config
+	DB     *sql.DB
+	Weight int //weight in config,at least 1
+}
+
+// Balancer picks a reader from healthy replicas for Slave().
+// key is the argument of SlaveWithKey,it's empty for Slave().
+// It returns the index of replicas and must be safe for concurrent use.
+type Balancer interface {
+	Pick(replicas []Replica, key string) int
+}
+
+// pick randomly.It's the default balancer.
+type randomBalancer struct{}
+
+func NewRandomBalancer() Balancer {
+	return randomBalancer{}
+}
+
+func (randomBalancer) Pick(replicas []Replica, key string) int {
+	return rand.Intn(len(replicas))
+}
+
+// smooth weighted round-robin like nginx.
+type weightedRoundRobinBalancer struct {
+	mu      sync.Mutex
+	current map[int]int //current weight of reader index
+}
+
+func NewWeightedRoundRobinBalancer() Balancer {
+	return &weightedRoundRobinBalancer{current: make(map[int]int)}
+}
+
+func (b *weightedRoundRobinBalancer) Pick(replicas []Replica, key string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total := 0
+	best := 0
+	for i, replica := range replicas {
+		b.current[replica.Index] += replica.Weight
+		total += replica.Weight
+		if b.current[replica.Index] > b.current[replicas[best].Index] {
+			best = i
+		}
+	}
+	b.current[replicas[best].Index] -= total
+	return best
+}
+
+// pick the reader with least in-use connections.
+type leastInUseBalancer struct{}
+
+func NewLeastInUseBalancer() Balancer {
+	return leastInUseBalancer{}
+}
+
+func (leastInUseBalancer) Pick(replicas []Replica, key string) int {
+	var candidates []int
+	least := math.MaxInt32
+	for i, replica := range replicas {
+		inUse := replica.DB.Stats().InUse
+		if inUse < least {
+			least = inUse
+			candidates = candidates[:0]
+		}
+		if inUse == least {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// consistent hash by key with weighted rendezvous hashing.
+// The same key always goes to the same reader while it's healthy,
+// only the keys of the ejected reader are moved.
+type consistentHashBalancer struct{}
+
+func NewConsistentHashBalancer() Balancer {
+	return consistentHashBalancer{}
+}
+
+func (consistentHashBalancer) Pick(replicas []Replica, key string) int {
+	if key == "" {
+		return rand.Intn(len(replicas))
+	}
+	best := 0
+	bestScore := math.Inf(-1)
+	for i, replica := range replicas {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte("#" + strconv.Itoa(replica.Index)))
+		// map the hash into (0,1)
+		f := (float64(h.Sum64()>>11) + 0.5) / float64(1<<53)
+		score := -float64(replica.Weight) / math.Log(f)
+		if score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+	return best
+}
@@ -0,0 +1,45 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-03
+ */
+package sql
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestWeightedRoundRobinBalancer(t *testing.T) {
+	replicas := []Replica{{Index: 0, Weight: 3}, {Index: 1, Weight: 1}}
+	b := NewWeightedRoundRobinBalancer()
+	counts := make([]int, len(replicas))
+	for i := 0; i < 8; i++ {
+		counts[b.Pick(replicas, "")]++
+	}
+	if counts[0] != 6 || counts[1] != 2 {
+		t.Errorf("Weighted round-robin must follow the weights.got:%v", counts)
+	}
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	replicas := []Replica{{Index: 0, Weight: 1}, {Index: 1, Weight: 1}, {Index: 2, Weight: 1}}
+	b := NewConsistentHashBalancer()
+
+	picked := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		key := "user" + strconv.Itoa(i)
+		picked[key] = replicas[b.Pick(replicas, key)].Index
+		if replicas[b.Pick(replicas, key)].Index != picked[key] {
+			t.Fatalf("The same key must go to the same reader.key:%s", key)
+		}
+	}
+
+	// eject reader 1,only its keys move.
+	left := []Replica{replicas[0], replicas[2]}
+	for key, index := range picked {
+		if index != 1 && left[b.Pick(left, key)].Index != index {
+			t.Errorf("The key of healthy reader must not move.key:%s", key)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-19
+ */
+package sql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	defaultMaxPacket = 4 << 20 //default max_allowed_packet of mysql 5.7
+	maxPlaceholders  = 65535   //max placeholders of a prepared statement
+)
+
+// BatchOptions is the options of BatchInsert.
+type BatchOptions struct {
+	Ignore        bool     //INSERT IGNORE
+	UpdateColumns []string //ON DUPLICATE KEY UPDATE col=VALUES(col)
+	MaxPacket     int      //max bytes of a statement,it should not exceed max_allowed_packet.Default 4MB
+	ChunkSize     int      //max rows of a statement.0 means no limit except MaxPacket and placeholders
+}
+
+// BatchResult is the result of BatchInsert.
+type BatchResult struct {
+	AffectedCount int64   //total affected rows
+	InsertIDs     []int64 //the first insert ID of every chunk
+}
+
+// insert rows into table by multi-row INSERT statements.
+// rows is a slice of structs(or pointers to struct) or map[string]interface{},
+// the struct fields are mapped to columns like ScanOne.
+// The rows are split into chunks by MaxPacket,ChunkSize and the placeholders limit.
+// The chunks are not atomic,run it in a transaction if needed.
+func (conn *Conn) BatchInsert(table string, columns []string, rows interface{}, opts *BatchOptions) (*BatchResult, error) {
+	return conn.BatchInsertContext(context.Background(), table, columns, rows, opts)
+}
+
+// batch insert with context.The timeout set by Timeout() applies to the whole batch.
+func (conn *Conn) BatchInsertContext(ctx context.Context, table string, columns []string, rows interface{}, opts *BatchOptions) (*BatchResult, error) {
+	ctx, cancel := conn.callContext(ctx)
+	defer cancel()
+	conn.timeout = 0
+
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	if len(columns) == 0 {
+		return nil, ERR_NOCOLUMNS
+	}
+	values, err := batchValues(rows, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPacket := opts.MaxPacket
+	if maxPacket <= 0 {
+		maxPacket = defaultMaxPacket
+	}
+	maxRows := maxPlaceholders / len(columns)
+	if opts.ChunkSize > 0 && opts.ChunkSize < maxRows {
+		maxRows = opts.ChunkSize
+	}
+
+	prefix, suffix := batchClauses(table, columns, opts)
+	rowHolder := "(" + strings.Repeat("?,", len(columns)-1) + "?)"
+	result := &BatchResult{}
+	for _, chunk := range splitChunks(values, maxRows, maxPacket-len(prefix)-len(suffix), len(rowHolder)+1) {
+		args := make([]interface{}, 0, len(chunk)*len(columns))
+		holders := make([]string, 0, len(chunk))
+		for _, row := range chunk {
+			args = append(args, row...)
+			holders = append(holders, rowHolder)
+		}
+		query := prefix + strings.Join(holders, ",") + suffix
+
+		insertID, affectedCount, err := conn.Prepared(query, args...).exec(ctx)
+		if err != nil {
+			return result, err
+		}
+		result.AffectedCount += affectedCount
+		result.InsertIDs = append(result.InsertIDs, insertID)
+	}
+	return result, nil
+}
+
+// split values into chunks which have at most maxRows rows and maxSize bytes.
+// The size of a row is estimated by the interpolated values and rowOverhead.
+// A chunk has one row at least even if it exceeds maxSize.
+func splitChunks(values [][]interface{}, maxRows int, maxSize int, rowOverhead int) [][][]interface{} {
+	var chunks [][][]interface{}
+	for start := 0; start < len(values); {
+		size := 0
+		end := start
+		for end < len(values) && end-start < maxRows {
+			rowSize := rowOverhead
+			for _, v := range values[end] {
+				rowSize += len(quoteArg(v))
+			}
+			if end > start && size+rowSize > maxSize {
+				break
+			}
+			size += rowSize
+			end++
+		}
+		chunks = append(chunks, values[start:end])
+		start = end
+	}
+	return chunks
+}
+
+// build the INSERT and ON DUPLICATE KEY UPDATE clauses.
+func batchClauses(table string, columns []string, opts *BatchOptions) (string, string) {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	insert := "INSERT INTO "
+	if opts.Ignore {
+		insert = "INSERT IGNORE INTO "
+	}
+	prefix := insert + quoteIdentifier(table) + " (" + strings.Join(quoted, ",") + ") VALUES "
+
+	if len(opts.UpdateColumns) == 0 {
+		return prefix, ""
+	}
+	updates := make([]string, len(opts.UpdateColumns))
+	for i, column := range opts.UpdateColumns {
+		updates[i] = quoteIdentifier(column) + "=VALUES(" + quoteIdentifier(column) + ")"
+	}
+	return prefix, " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ",")
+}
+
+// quote the identifier with backquotes,the db.table is quoted separately.
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.Replace(strings.Trim(part, "`"), "`", "``", -1) + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// get the values of columns from the slice of structs or maps.
+func batchValues(rows interface{}, columns []string) ([][]interface{}, error) {
+	sliceVal := reflect.ValueOf(rows)
+	if sliceVal.Kind() != reflect.Slice {
+		return nil, ERR_MUSTBESLICE
+	}
+
+	values := make([][]interface{}, sliceVal.Len())
+	for i := 0; i < sliceVal.Len(); i++ {
+		item := reflect.Indirect(sliceVal.Index(i))
+		if item.Kind() == reflect.Interface {
+			item = reflect.Indirect(item.Elem())
+		}
+		row := make([]interface{}, len(columns))
+		switch item.Kind() {
+		case reflect.Map:
+			for j, column := range columns {
+				v := item.MapIndex(reflect.ValueOf(column))
+				if !v.IsValid() {
+					return nil, fmt.Errorf("column %q is not found in row %d", column, i)
+				}
+				row[j] = v.Interface()
+			}
+		case reflect.Struct:
+			meta := getStructMeta(item.Type())
+			for j, column := range columns {
+				f, ok := meta.field(column)
+				if !ok {
+					return nil, fmt.Errorf("column %q is not found in %s", column, item.Type().String())
+				}
+				// the field of nil embedded pointer is NULL
+				if v := fieldByIndex(item, f.index, false); v.IsValid() {
+					row[j] = v.Interface()
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%q must be a struct or map", item.Kind().String())
+		}
+		values[i] = row
+	}
+	return values, nil
+}
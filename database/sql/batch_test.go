@@ -0,0 +1,63 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-19
+ */
+package sql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchValues(t *testing.T) {
+	now := time.Now()
+	users := []*Usertest{
+		{Mobile: "18600000000", Nickname: "a", CreateTime: now},
+		{Mobile: "18600000001", Nickname: "b", CreateTime: now},
+	}
+	values, err := batchValues(users, []string{"mobile", "nickname", "create_time"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[1][0] != "18600000001" || values[1][1] != "b" || values[1][2] != now {
+		t.Errorf("Batch values of struct mismatch.got:%v", values)
+	}
+
+	maps := []map[string]interface{}{{"mobile": "18600000000", "nickname": "a"}}
+	if _, err = batchValues(maps, []string{"mobile", "create_time"}); err == nil {
+		t.Error("The missing column of map must return an error.")
+	}
+}
+
+func TestBatchClauses(t *testing.T) {
+	prefix, suffix := batchClauses("test.usertest", []string{"mobile", "nickname"},
+		&BatchOptions{Ignore: true, UpdateColumns: []string{"nickname"}})
+	if prefix != "INSERT IGNORE INTO `test`.`usertest` (`mobile`,`nickname`) VALUES " {
+		t.Errorf("Insert clause mismatch.got:%s", prefix)
+	}
+	if suffix != " ON DUPLICATE KEY UPDATE `nickname`=VALUES(`nickname`)" {
+		t.Errorf("Update clause mismatch.got:%s", suffix)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	var values [][]interface{}
+	for i := 0; i < 10; i++ {
+		values = append(values, []interface{}{"0123456789", i})
+	}
+
+	// every row is about 20 bytes.
+	chunks := splitChunks(values, 4, 50, 6)
+	if len(chunks) != 5 || len(chunks[0]) != 2 {
+		t.Errorf("The chunks must be limited by size.got:%d chunks", len(chunks))
+	}
+	chunks = splitChunks(values, 4, 1<<20, 6)
+	if len(chunks) != 3 || len(chunks[2]) != 2 {
+		t.Errorf("The chunks must be limited by rows.got:%d chunks", len(chunks))
+	}
+	chunks = splitChunks(values[:1], 4, 1, 6)
+	if len(chunks) != 1 {
+		t.Error("The row exceeds the size must be in its own chunk.")
+	}
+}
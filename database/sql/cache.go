@@ -0,0 +1,200 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-06-20
+ */
+package sql
+
+import (
+	"crypto/md5"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"github.com/AbelZhou/even/database"
+	"github.com/vmihailenco/msgpack"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	cacheKeyPrefix     = "even:sql:"
+	tableVersionPrefix = "even:sqltable:"
+
+	scopeFetchOne = "fetchone"
+	scopeFetchAll = "fetchall"
+	scopeScanOne  = "scanone"
+	scopeScanAll  = "scanall"
+)
+
+// build the cache key by the query scope, normalized sql, args and the versions of tables.
+// The key is hashed so that it is always a valid memcache key.
+// The args are converted as the driver does,so a pointer arg is keyed by the value it points to.
+func buildCacheKey(scope string, sql string, args []interface{}, versions []string) string {
+	h := md5.New()
+	_, _ = fmt.Fprintf(h, "%s|%s", scope, normalizeSQL(sql))
+	for _, arg := range args {
+		if v, err := driver.DefaultParameterConverter.ConvertValue(arg); err == nil {
+			arg = v
+		}
+		_, _ = fmt.Fprintf(h, "|%T:%v", arg, arg)
+	}
+	for _, version := range versions {
+		_, _ = fmt.Fprintf(h, "|%s", version)
+	}
+	return cacheKeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// get the versions of tables.
+// The missing version is initialized with a new one,so the entries cached
+// before the version was evicted can never be hit again.
+func tableVersions(cache database.Cache, tables []string) []string {
+	versions := make([]string, 0, len(tables))
+	for _, table := range tables {
+		v := cache.Get(tableVersionPrefix + table)
+		if v == nil {
+			v = bumpTableVersion(cache, table)
+		}
+		versions = append(versions, fmt.Sprintf("%s@%v", table, v))
+	}
+	return versions
+}
+
+// set a new version of table.All the cached results depend on the table become invalid.
+func bumpTableVersion(cache database.Cache, table string) string {
+	version := strconv.FormatInt(time.Now().UnixNano(), 36)
+	cache.Set(tableVersionPrefix+table, version)
+	return version
+}
+
+// parse the tables which the sql reads or writes.
+// It only recognizes the tables after FROM, JOIN, UPDATE and INTO.
+// Subqueries are parsed by their own FROM clause.
+func parseTables(sql string) []string {
+	tokens := tokenizeSQL(sql)
+	var tables []string
+	seen := make(map[string]bool)
+	add := func(table string) {
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		keyword := strings.ToLower(tokens[i])
+		if keyword != "from" && keyword != "join" && keyword != "update" && keyword != "into" {
+			continue
+		}
+		for i+1 < len(tokens) && isIdentifier(tokens[i+1]) {
+			i++
+			add(tableName(tokens[i]))
+			// table list: FROM a [AS] x, b y
+			if keyword != "from" && keyword != "update" {
+				break
+			}
+			if i+1 < len(tokens) && strings.EqualFold(tokens[i+1], "as") {
+				i++
+			}
+			if i+1 < len(tokens) && isIdentifier(tokens[i+1]) && !sqlKeywords[strings.ToLower(tokens[i+1])] {
+				i++
+			}
+			if i+1 >= len(tokens) || tokens[i+1] != "," {
+				break
+			}
+			i++
+		}
+	}
+	return tables
+}
+
+// keywords which may follow a table name.
+var sqlKeywords = map[string]bool{
+	"where": true, "set": true, "join": true, "left": true, "right": true, "inner": true,
+	"outer": true, "cross": true, "straight_join": true, "natural": true, "on": true,
+	"using": true, "group": true, "order": true, "limit": true, "having": true, "union": true,
+	"for": true, "lock": true, "values": true, "value": true, "select": true, "partition": true,
+	"force": true, "ignore": true, "use": true, "window": true, "into": true,
+}
+
+// split sql into identifiers and punctuations.String literals are dropped.
+func tokenizeSQL(sql string) []string {
+	var tokens []string
+	runes := []rune(sql)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == '\'' || r == '"':
+			// skip string literal
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+		case isIdentRune(r) || r == '`':
+			start := i
+			for ; i < len(runes); i++ {
+				if runes[i] == '`' {
+					for i++; i < len(runes) && runes[i] != '`'; i++ {
+					}
+					continue
+				}
+				if !isIdentRune(runes[i]) && runes[i] != '.' {
+					break
+				}
+			}
+			tokens = append(tokens, string(runes[start:i]))
+			i--
+		default:
+			tokens = append(tokens, string(r))
+		}
+	}
+	return tokens
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isIdentifier(token string) bool {
+	r := []rune(token)[0]
+	return r == '`' || isIdentRune(r)
+}
+
+// remove the backquotes and lower the table name.
+func tableName(token string) string {
+	return strings.ToLower(strings.Replace(token, "`", "", -1))
+}
+
+// collapse the whitespace of sql so that the same query written in
+// different layouts shares one cache entry.
+func normalizeSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// fill the cached slice into out which must be a pointer to slice.
+// The in-process cache returns the same type so it's copied directly,
+// others (eg: memcache) are decoded to generic types and converted by msgpack.
+func fillFromCache(out interface{}, cached interface{}) bool {
+	outVal := reflect.ValueOf(out).Elem()
+	cachedVal := reflect.ValueOf(cached)
+	if cachedVal.Type().AssignableTo(outVal.Type()) {
+		cp := reflect.MakeSlice(outVal.Type(), cachedVal.Len(), cachedVal.Len())
+		reflect.Copy(cp, cachedVal)
+		outVal.Set(cp)
+		return true
+	}
+
+	b, err := msgpack.Marshal(cached)
+	if err == nil {
+		err = msgpack.Unmarshal(b, out)
+	}
+	if err != nil {
+		outVal.Set(reflect.Zero(outVal.Type()))
+		return false
+	}
+	return true
+}
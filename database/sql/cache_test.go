@@ -0,0 +1,135 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-06-20
+ */
+package sql
+
+import (
+	"database/sql/driver"
+	"github.com/AbelZhou/even/cache"
+	"github.com/AbelZhou/even/database"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTables(t *testing.T) {
+	cases := map[string][]string{
+		"SELECT * FROM `usertest` WHERE `id`=?":                                  {"usertest"},
+		"select a.id from user a left join `order` o on o.uid=a.id":              {"user", "order"},
+		"SELECT * FROM a AS x, b y WHERE x.id=y.id":                              {"a", "b"},
+		"SELECT * FROM test.usertest WHERE nickname='from abc'":                  {"test.usertest"},
+		"SELECT * FROM (SELECT id FROM usertest) t":                              {"usertest"},
+		"INSERT INTO `usertest` values(null,?,?,?,?)":                            {"usertest"},
+		"UPDATE usertest SET nickname=? WHERE id IN (SELECT uid FROM blacklist)": {"usertest", "blacklist"},
+		"DELETE FROM `usertest` WHERE `id`=?":                                    {"usertest"},
+		"SELECT 1":                                                               nil,
+	}
+	for sql, expect := range cases {
+		if tables := parseTables(sql); !reflect.DeepEqual(tables, expect) {
+			t.Errorf("Parse tables failed.sql:%s expect:%v got:%v", sql, expect, tables)
+		}
+	}
+}
+
+func TestCacheKey_Invalidate(t *testing.T) {
+	gc := cache.NewGCache(100)
+	sql := "SELECT * FROM `usertest` WHERE `id`=?"
+
+	key1 := buildCacheKey(scopeFetchAll, sql, []interface{}{1}, tableVersions(gc, parseTables(sql)))
+	key2 := buildCacheKey(scopeFetchAll, "SELECT *  FROM `usertest`\n WHERE `id`=?", []interface{}{1}, tableVersions(gc, parseTables(sql)))
+	if key1 != key2 {
+		t.Errorf("The same query must share one key.%s %s", key1, key2)
+	}
+	if key1 == buildCacheKey(scopeFetchAll, sql, []interface{}{2}, tableVersions(gc, parseTables(sql))) {
+		t.Error("Different args must have different keys.")
+	}
+	if key1 == buildCacheKey(scopeFetchOne, sql, []interface{}{1}, tableVersions(gc, parseTables(sql))) {
+		t.Error("Different scopes must have different keys.")
+	}
+	id := int64(1)
+	if key1 != buildCacheKey(scopeFetchAll, sql, []interface{}{&id}, tableVersions(gc, parseTables(sql))) {
+		t.Error("The pointer arg must be keyed by its value.")
+	}
+
+	conn := &Conn{cache: gc}
+	conn.invalidate(parseTables("DELETE FROM `usertest` WHERE `id`=?"))
+	if key1 == buildCacheKey(scopeFetchAll, sql, []interface{}{1}, tableVersions(gc, parseTables(sql))) {
+		t.Error("The key must be changed after the table was written.")
+	}
+}
+
+func TestConn_Cache(t *testing.T) {
+	pool, err := OpenPool(&database.Config{Write: &database.DBConfig{DSN: "slow"}}, "even_test_slow", PoolOptions{Lazy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	pool.SetCache(cache.NewGCache(100), 0)
+	defer func() { onRow = nil }()
+	queries := 0
+	onRow = func(n int) error {
+		if n == 0 {
+			queries++
+		}
+		return nil
+	}
+	fetch := func(conn *Conn, args ...interface{}) {
+		t.Helper()
+		if res, err := conn.Prepared("SELECT id FROM slow WHERE id > ?", args...).FetchAll(); err != nil || len(res) != 10 {
+			t.Fatalf("Fetch failed.got:%d rows,%v", len(res), err)
+		}
+	}
+
+	id := int64(1)
+	fetch(pool.Master(), &id)
+	fetch(pool.Master(), &id)
+	if queries != 1 {
+		t.Errorf("The second query must hit the cache.queries:%d", queries)
+	}
+	id = 2
+	fetch(pool.Master(), &id)
+	if queries != 2 {
+		t.Errorf("The changed pointer arg must miss the cache.queries:%d", queries)
+	}
+	fetch(pool.Master().NoCache(), &id)
+	if queries != 3 {
+		t.Errorf("NoCache must skip the cache.queries:%d", queries)
+	}
+
+	fetch(pool.Master().Cache(1), 3)
+	fetch(pool.Master(), 3)
+	if queries != 4 {
+		t.Errorf("The entry must be hit before it expires.queries:%d", queries)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	fetch(pool.Master(), 3)
+	if queries != 5 {
+		t.Errorf("The expired entry must miss the cache.queries:%d", queries)
+	}
+
+	// the partial rows of the broken reading must not be cached
+	onRow = func(n int) error {
+		if n == 0 {
+			queries++
+		}
+		if n == 3 {
+			return driver.ErrBadConn
+		}
+		return nil
+	}
+	if res, err := pool.Master().Prepared("SELECT id FROM slow WHERE id > ?", 4).FetchAll(); err == nil || res != nil {
+		t.Fatalf("The broken reading must return an error.got:%d rows,%v", len(res), err)
+	}
+	onRow = func(n int) error {
+		if n == 0 {
+			queries++
+		}
+		return nil
+	}
+	fetch(pool.Master(), 4)
+	if queries != 7 {
+		t.Errorf("The partial rows must not be cached.queries:%d", queries)
+	}
+}
@@ -6,10 +6,12 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"github.com/AbelZhou/even/database"
 	"reflect"
-	"strings"
+	"time"
 )
 
 type Conn struct {
@@ -20,6 +22,25 @@ type Conn struct {
 	stmt          *sql.Stmt
 	preparedSql   string
 	args          []interface{}
+	timeout       time.Duration
+	cache         database.Cache
+	cacheExpire   int32 //default expire of cache.Second
+	expire        int32 //expire for the next query.Second
+	noCache       bool
+	cacheKey      string
+	tables        []string //declared tables for the next call
+	dirtyTables   []string //tables written in transaction
+	interceptors  []Interceptor
+	consistency   Consistency
+	session       *Session
+	txSession     *Session //session written in transaction
+	savepoints    []int    //length of dirtyTables when the savepoints were created
+	stmts         *stmtCache
+	releaseStmt   func()
+	interpolate   bool
+	strict        bool              //error on the columns not mapped to struct fields
+	prepareErr    error             //error of binding named args,returned by the next call
+	shardTables   map[string]string //logical table -> physical table of the shard
 }
 
 //Ping&Pong. Return true or false on current database connection.
@@ -32,45 +53,123 @@ func (conn *Conn) PING() bool {
 }
 
 //begin transaction
+//It creates a savepoint when the conn is already in transaction.
 func (conn *Conn) Begin() (err error) {
-	if conn.isReader {
+	return conn.BeginContext(context.Background())
+}
+
+//begin transaction with context.
+//The transaction will be rolled back if the context is canceled before commit.
+func (conn *Conn) BeginContext(ctx context.Context) (err error) {
+	return conn.BeginTx(ctx, nil)
+}
+
+//begin a read only transaction with repeatable read.
+//All the reads in it see the same snapshot,it can be used on reader connections for reporting.
+//The snapshot is established by the first read instead of Begin,
+//because START TRANSACTION WITH CONSISTENT SNAPSHOT is not supported by the driver.
+//The writes committed between Begin and the first read are visible.
+func (conn *Conn) BeginSnapshot(ctx context.Context) (err error) {
+	return conn.BeginTx(ctx, &TxOptions{Isolation: LevelRepeatableRead, ReadOnly: true})
+}
+
+//begin transaction with options.
+//The options are ignored when it creates a savepoint.
+func (conn *Conn) BeginTx(ctx context.Context, opts *TxOptions) (err error) {
+	if conn.isReader && (opts == nil || !opts.ReadOnly) {
 		return ERR_READERTRANSACTION
 	}
-	conn.inTransaction = true
-	conn.tx, err = conn.db.Begin()
+	ctx, event, err := conn.intercept(ctx, OpBegin)
+	defer func() { conn.complete(ctx, event, err) }()
 	if err != nil {
-		return
+		return err
+	}
+	if conn.inTransaction {
+		return conn.savepoint(ctx, event)
 	}
 
+	var txOpts *sql.TxOptions
+	if opts != nil {
+		txOpts = &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	}
+	conn.tx, err = conn.db.BeginTx(ctx, txOpts)
+	if err != nil {
+		conn.tx = nil
+		return contextErr(ctx, err)
+	}
+	conn.inTransaction = true
+
 	return
 }
 
 //commit transaction
+//It releases the latest savepoint in nested transaction.
 func (conn *Conn) Commit() (err error) {
+	ctx, event, err := conn.intercept(context.Background(), OpCommit)
+	defer func() { conn.complete(ctx, event, err) }()
+	if err != nil {
+		return err
+	}
+	if len(conn.savepoints) > 0 {
+		return conn.releaseSavepoint(ctx, event)
+	}
+
 	//unlock
 	conn.inTransaction = false
 	//tx commit
 	if conn.tx != nil {
-		return conn.tx.Commit()
+		err = conn.tx.Commit()
+		conn.tx = nil
+	}
+	// invalidate the cache and record the session after the writes are visible.
+	if err == nil {
+		conn.invalidate(conn.dirtyTables)
+		if conn.txSession != nil {
+			conn.recordSession(ctx, conn.txSession)
+		}
 	}
+	conn.dirtyTables = nil
+	conn.txSession = nil
 	return
 }
 
 //rollback transaction
+//It rolls back to the latest savepoint in nested transaction.
 func (conn *Conn) Rollback() (err error) {
+	// rollback can not be aborted by interceptors,otherwise the transaction leaks.
+	ctx, event, _ := conn.intercept(context.Background(), OpRollback)
+	defer func() { conn.complete(ctx, event, err) }()
+	if len(conn.savepoints) > 0 {
+		return conn.rollbackSavepoint(ctx, event)
+	}
+
 	//unlock
 	conn.inTransaction = false
+	conn.dirtyTables = nil
+	conn.txSession = nil
 
 	//tx rollback
 	if conn.tx != nil {
-		return conn.tx.Rollback()
+		err = conn.tx.Rollback()
+		conn.tx = nil
 	}
-	return nil
+	return
+}
+
+// get the depth of transaction.
+// 0 means not in transaction,1 is the outermost transaction,and every savepoint adds 1.
+func (conn *Conn) TransactionDepth() int {
+	if !conn.inTransaction {
+		return 0
+	}
+	return len(conn.savepoints) + 1
 }
 
 // set prepared sql & data
+// The :name placeholders are bound by a map or struct,and the slice is expanded for IN (:ids).
+// eg: Prepared("SELECT * FROM users WHERE id IN (:ids)", map[string]interface{}{"ids": ids})
 func (conn *Conn) Prepared(sql string, args ...interface{}) *Conn {
-	sql, args = conn.beforePrepared(sql, args...)
+	sql, args, conn.prepareErr = conn.beforePrepared(sql, args...)
 
 	conn.preparedSql = sql
 	conn.args = args
@@ -79,19 +178,75 @@ func (conn *Conn) Prepared(sql string, args ...interface{}) *Conn {
 	return conn
 }
 
+// Statement is the SQL built by a query builder,eg: the builders of database/dsl.
+type Statement interface {
+	Build() (string, []interface{}, error)
+}
+
+// set the SQL & data built by stmt.
+// The build error is returned by the next call.
+func (conn *Conn) PreparedStatement(stmt Statement) *Conn {
+	sql, args, err := stmt.Build()
+	conn.Prepared(sql, args...)
+	if err != nil {
+		conn.prepareErr = err
+	}
+	return conn
+}
+
+// set timeout for the next query or execute call.
+// It will be reset after the call.
+func (conn *Conn) Timeout(timeout time.Duration) *Conn {
+	conn.timeout = timeout
+	return conn
+}
+
+// set cache expire for the next query.Second
+// It only works when the pool has a cache.
+func (conn *Conn) Cache(expire int32) *Conn {
+	conn.expire = expire
+	return conn
+}
+
+// declare the tables which the next call reads or writes.
+// Tables are parsed from the sql by default,use it when the parser can not recognize them(eg: views).
+func (conn *Conn) Tables(tables ...string) *Conn {
+	conn.tables = tables
+	return conn
+}
+
+// do not read or write cache for the next query.
+func (conn *Conn) NoCache() *Conn {
+	conn.noCache = true
+	return conn
+}
+
 // get first row.
 // create new prepared statement object in every call.
 func (conn *Conn) FetchOne() (res map[string]interface{}, err error) {
+	return conn.FetchOneContext(context.Background())
+}
+
+// get first row with context.
+func (conn *Conn) FetchOneContext(ctx context.Context) (res map[string]interface{}, err error) {
 	defer conn.clear()
+	ctx, cancel := conn.callContext(ctx)
+	defer cancel()
+	ctx, event, err := conn.intercept(ctx, OpQuery)
+	defer func() { conn.complete(ctx, event, err) }()
+	if err != nil {
+		return nil, err
+	}
 
 	// get data from cacher
-	cacheData := conn.beforeQuery()
-	if cacheData != nil {
-		data := cacheData.([]map[string]interface{})
+	cacheData := conn.beforeQuery(scopeFetchOne)
+	if data, ok := cacheData.([]map[string]interface{}); ok && len(data) > 0 {
+		event.Cached = true
+		event.RowCount = 1
 		return data[0], nil
 	}
 
-	rows, err := conn.query()
+	rows, err := conn.query(ctx)
 	if err != nil {
 		//process error
 		return nil, err
@@ -99,44 +254,63 @@ func (conn *Conn) FetchOne() (res map[string]interface{}, err error) {
 
 	ress, err := buildResultMap(rows, true)
 	if err != nil {
-		return nil, err
+		return nil, contextErr(ctx, err)
 	}
 
 	if len(ress) == 0 {
 		return nil, nil
 	}
+	event.RowCount = 1
 	conn.afterQuery(ress)
 	return ress[0], nil
 }
 
 // get all rows.
 func (conn *Conn) FetchAll() (res []map[string]interface{}, err error) {
+	return conn.FetchAllContext(context.Background())
+}
+
+// get all rows with context.
+func (conn *Conn) FetchAllContext(ctx context.Context) (res []map[string]interface{}, err error) {
 	defer conn.clear()
+	ctx, cancel := conn.callContext(ctx)
+	defer cancel()
+	ctx, event, err := conn.intercept(ctx, OpQuery)
+	defer func() { conn.complete(ctx, event, err) }()
+	if err != nil {
+		return nil, err
+	}
 
 	// get data from cacher
-	cacheData := conn.beforeQuery()
-	if cacheData != nil {
-		data := cacheData.([]map[string]interface{})
+	cacheData := conn.beforeQuery(scopeFetchAll)
+	if data, ok := cacheData.([]map[string]interface{}); ok {
+		event.Cached = true
+		event.RowCount = int64(len(data))
 		return data, nil
 	}
 
-	rows, err := conn.query()
+	rows, err := conn.query(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	res, err = buildResultMap(rows, false)
 	if err != nil {
-		return nil, err
+		return nil, contextErr(ctx, err)
 	}
 
+	event.RowCount = int64(len(res))
 	conn.afterQuery(res)
 	return res, err
 }
 
 // get one raw to a struct.
-// no cache
 func (conn *Conn) ScanOne(v interface{}) error {
+	return conn.ScanOneContext(context.Background(), v)
+}
+
+// get one raw to a struct with context.
+func (conn *Conn) ScanOneContext(ctx context.Context, v interface{}) (err error) {
 	// check v
 	vType := reflect.TypeOf(v)
 	if k := vType.Kind(); k != reflect.Ptr {
@@ -151,23 +325,32 @@ func (conn *Conn) ScanOne(v interface{}) error {
 
 	// defer database clear
 	defer conn.clear()
+	ctx, cancel := conn.callContext(ctx)
+	defer cancel()
+	ctx, event, err := conn.intercept(ctx, OpQuery)
+	defer func() { conn.complete(ctx, event, err) }()
+	if err != nil {
+		return err
+	}
 
-	//cacheData := db.beforeQuery()
-	//if cacheData != nil {
-	//	v = cacheData
-	//	return nil
-	//}
+	sl := reflect.New(reflect.SliceOf(vType))
+	cacheData := conn.beforeQuery(scopeScanOne)
+	if cacheData != nil && fillFromCache(sl.Interface(), cacheData) && sl.Elem().Len() > 0 {
+		event.Cached = true
+		event.RowCount = 1
+		vVal.Set(sl.Elem().Index(0))
+		return nil
+	}
 
 	//query
-	rows, err := conn.query()
+	rows, err := conn.query(ctx)
 	if err != nil {
 		return err
 	}
 
 	// fill obj
-	sl := reflect.New(reflect.SliceOf(vType))
-	if err = fillRows(sl.Interface(), rows); err != nil {
-		return err
+	if err = fillRows(sl.Interface(), rows, conn.strict); err != nil {
+		return contextErr(ctx, err)
 	}
 	sl = sl.Elem()
 
@@ -177,101 +360,251 @@ func (conn *Conn) ScanOne(v interface{}) error {
 
 	vVal.Set(sl.Index(0))
 
-	//db.afterQuery(sl)
-	//time.Sleep(2000*time.Second)
+	event.RowCount = 1
+	conn.afterQuery(sl.Slice(0, 1).Interface())
 	return nil
 }
 
 // get one raw to a struct slice
 func (conn *Conn) ScanAll(out interface{}) error {
+	return conn.ScanAllContext(context.Background(), out)
+}
+
+// get rows to a struct slice with context.
+func (conn *Conn) ScanAllContext(ctx context.Context, out interface{}) (err error) {
 	// defer database clear
 	defer conn.clear()
-	rows, err := conn.query()
+	ctx, cancel := conn.callContext(ctx)
+	defer cancel()
+
+	outType := reflect.TypeOf(out)
+	if outType.Kind() != reflect.Ptr {
+		return ERR_MUSTBEPOINTER
+	}
+	if outType.Elem().Kind() != reflect.Slice {
+		return ERR_MUSTBESLICE
+	}
+
+	ctx, event, err := conn.intercept(ctx, OpQuery)
+	defer func() { conn.complete(ctx, event, err) }()
 	if err != nil {
 		return err
 	}
 
-	// fill obj
-	if err = fillRows(out, rows); err != nil {
+	cacheData := conn.beforeQuery(scopeScanAll)
+	if cacheData != nil && fillFromCache(out, cacheData) {
+		event.Cached = true
+		event.RowCount = int64(reflect.ValueOf(out).Elem().Len())
+		return nil
+	}
+
+	rows, err := conn.query(ctx)
+	if err != nil {
 		return err
 	}
 
+	// fill obj
+	if err = fillRows(out, rows, conn.strict); err != nil {
+		return contextErr(ctx, err)
+	}
+
+	event.RowCount = int64(reflect.ValueOf(out).Elem().Len())
+	conn.afterQuery(reflect.ValueOf(out).Elem().Interface())
 	return nil
 }
 
 //get last insert ID.
 func (conn *Conn) LastInsertID() (int64, error) {
-	defer conn.clear()
+	return conn.LastInsertIDContext(context.Background())
+}
 
-	conn.beforeExecute()
-	res, err := conn.execute()
-	if err != nil {
-		return 0, err
-	}
-	lastInsertID, err := res.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-	conn.afterExecute()
+//get last insert ID with context.
+func (conn *Conn) LastInsertIDContext(ctx context.Context) (int64, error) {
+	lastInsertID, _, err := conn.exec(ctx)
 	return lastInsertID, err
 }
 
 //get affected count
 func (conn *Conn) AffectedCount() (int64, error) {
+	return conn.AffectedCountContext(context.Background())
+}
+
+//get affected count with context.
+func (conn *Conn) AffectedCountContext(ctx context.Context) (int64, error) {
+	_, affectedCount, err := conn.exec(ctx)
+	return affectedCount, err
+}
+
+//execute the prepared sql with hooks.
+func (conn *Conn) exec(ctx context.Context) (lastInsertID int64, affectedCount int64, err error) {
 	defer conn.clear()
+	ctx, cancel := conn.callContext(ctx)
+	defer cancel()
+	ctx, event, err := conn.intercept(ctx, OpExecute)
+	defer func() { conn.complete(ctx, event, err) }()
+	if err != nil {
+		return 0, 0, err
+	}
 
 	conn.beforeExecute()
-	res, err := conn.execute()
+	res, err := conn.execute(ctx)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
-	affectedCount, err := res.RowsAffected()
-	if err != nil {
-		return 0, err
+	if lastInsertID, err = res.LastInsertId(); err != nil {
+		return 0, 0, err
+	}
+	if affectedCount, err = res.RowsAffected(); err != nil {
+		return 0, 0, err
 	}
+	event.LastInsertID = lastInsertID
+	event.RowCount = affectedCount
 	conn.afterExecute()
-	return affectedCount, err
+	conn.recordWrite(ctx)
+	return lastInsertID, affectedCount, nil
 }
 
 // hook begin
-func (conn *Conn) beforePrepared(sql string, args ...interface{}) (string, []interface{}) {
-	return sql, args
+// rewrite the named placeholders to positional and the logical tables to physical.
+func (conn *Conn) beforePrepared(sql string, args ...interface{}) (string, []interface{}, error) {
+	// bind before rewriting,or :user is rewritten to :user_07 when user is a logical table
+	sql, args, err := bindNamed(sql, args)
+	if err != nil {
+		return "", nil, err
+	}
+	return rewriteTables(sql, conn.shardTables), args, nil
 }
 
 func (conn *Conn) afterPrepared() {
 
 }
 
-func (conn *Conn) beforeQuery() interface{} {
-	// get cache data from cacher.
-	return nil
+// get cache data from cacher.
+// The query in transaction never hits the cache.
+func (conn *Conn) beforeQuery(scope string) interface{} {
+	conn.cacheKey = ""
+	if conn.cache == nil || conn.noCache || conn.inTransaction {
+		return nil
+	}
+	versions := tableVersions(conn.cache, conn.affectTables())
+	conn.cacheKey = buildCacheKey(scope, conn.preparedSql, conn.args, versions)
+	return conn.cache.Get(conn.cacheKey)
 }
 
+// put the query result into cacher.
+// Empty result will not be cached.
 func (conn *Conn) afterQuery(queryResult interface{}) {
+	if conn.cacheKey == "" || queryResult == nil {
+		return
+	}
+	if v := reflect.ValueOf(queryResult); v.Kind() == reflect.Slice && v.Len() == 0 {
+		return
+	}
+
+	expire := conn.cacheExpire
+	if conn.expire > 0 {
+		expire = conn.expire
+	}
+	if expire > 0 {
+		conn.cache.SetWithExpire(conn.cacheKey, queryResult, expire)
+	} else {
+		conn.cache.Set(conn.cacheKey, queryResult)
+	}
 }
 
 func (conn *Conn) beforeExecute() {
 
 }
 
+// invalidate the cached results depend on the written tables.
+// It's deferred until commit in transaction.
 func (conn *Conn) afterExecute() {
-
+	if conn.cache == nil {
+		return
+	}
+	tables := conn.affectTables()
+	if conn.inTransaction {
+		conn.dirtyTables = append(conn.dirtyTables, tables...)
+		return
+	}
+	conn.invalidate(tables)
 }
 
 // hook end
 
+// get the declared tables or parse them from the prepared sql.
+func (conn *Conn) affectTables() []string {
+	if len(conn.tables) > 0 {
+		tables := make([]string, len(conn.tables))
+		for i, table := range conn.tables {
+			tables[i] = tableName(table)
+		}
+		return tables
+	}
+	return parseTables(conn.preparedSql)
+}
+
+// bump the versions of tables.
+func (conn *Conn) invalidate(tables []string) {
+	if conn.cache == nil {
+		return
+	}
+	seen := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		if seen[table] {
+			continue
+		}
+		seen[table] = true
+		bumpTableVersion(conn.cache, table)
+	}
+}
+
 // clear prepared sql and args
 func (conn *Conn) clear() {
 	conn.preparedSql = ""
 	conn.args = nil
-	// After rows object have been closed.We must close prepared statement
-	if conn.stmt != nil {
-		conn.stmt.Close()
+	conn.prepareErr = nil
+	conn.timeout = 0
+	conn.expire = 0
+	conn.noCache = false
+	conn.cacheKey = ""
+	conn.tables = nil
+	// After rows object have been closed.We must close or release prepared statement
+	if conn.releaseStmt != nil {
+		conn.releaseStmt()
+		conn.releaseStmt = nil
+	}
+	conn.stmt = nil
+}
+
+// build the context for a single call.
+// The timeout set by Timeout() is applied on the parent context.
+func (conn *Conn) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	if conn.timeout > 0 {
+		return context.WithTimeout(ctx, conn.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// convert the error caused by context to ERR_TIMEOUT or ERR_CANCELED.
+func contextErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return ERR_TIMEOUT
+	case context.Canceled:
+		return ERR_CANCELED
+	}
+	return err
 }
 
 //query data
-func (conn *Conn) query() (sqlRows *sql.Rows, err error) {
+func (conn *Conn) query(ctx context.Context) (sqlRows *sql.Rows, err error) {
 	if conn.preparedSql == "" {
 		return nil, ERR_NOPREPARED
 	}
@@ -279,57 +612,99 @@ func (conn *Conn) query() (sqlRows *sql.Rows, err error) {
 	var (
 		rows *sql.Rows
 	)
-	//create new statement pointer
-	if conn.inTransaction {
-		conn.stmt, err = conn.tx.Prepare(conn.preparedSql)
-	} else {
-		conn.stmt, err = conn.db.Prepare(conn.preparedSql)
-	}
-
-	if err != nil {
-		return
+	if conn.interpolate {
+		// the args are interpolated by driver without server side prepare.
+		if conn.inTransaction {
+			rows, err = conn.tx.QueryContext(ctx, conn.preparedSql, conn.args...)
+		} else {
+			rows, err = conn.db.QueryContext(ctx, conn.preparedSql, conn.args...)
+		}
+		return rows, contextErr(ctx, err)
 	}
 
-	//logic
-	if len(conn.args) == 0 {
-		rows, err = conn.stmt.Query()
-	} else {
-		rows, err = conn.stmt.Query(conn.args...)
+	if err = conn.prepare(ctx); err != nil {
+		return nil, contextErr(ctx, err)
 	}
 
+	rows, err = conn.stmt.QueryContext(ctx, conn.args...)
 	if err != nil {
-		return
+		return nil, contextErr(ctx, err)
 	}
 
 	return rows, nil
 }
 
 //execute prepared sql
-func (conn *Conn) execute() (sql.Result, error) {
+func (conn *Conn) execute(ctx context.Context) (sql.Result, error) {
 	if conn.preparedSql == "" {
 		return nil, ERR_NOPREPARED
 	}
 
 	var (
-		err error
+		result sql.Result
+		err    error
 	)
-	if conn.inTransaction {
-		conn.stmt, err = conn.tx.Prepare(conn.preparedSql)
-	} else {
-		conn.stmt, err = conn.db.Prepare(conn.preparedSql)
+	if conn.interpolate {
+		if conn.inTransaction {
+			result, err = conn.tx.ExecContext(ctx, conn.preparedSql, conn.args...)
+		} else {
+			result, err = conn.db.ExecContext(ctx, conn.preparedSql, conn.args...)
+		}
+		return result, contextErr(ctx, err)
 	}
-	if err != nil {
-		return nil, err
+
+	if err = conn.prepare(ctx); err != nil {
+		return nil, contextErr(ctx, err)
 	}
 
-	result, err := conn.stmt.Exec(conn.args...)
+	result, err = conn.stmt.ExecContext(ctx, conn.args...)
 	if err != nil {
-		return nil, err
+		return nil, contextErr(ctx, err)
 	}
 
 	return result, err
 }
 
+//create the prepared statement or get it from the statement cache.
+//The statement of cache is rebound to the transaction by tx.Stmt.
+//The statement missing in cache is prepared on the transaction,
+//preparing it on the DB needs another connection which may be exhausted.
+func (conn *Conn) prepare(ctx context.Context) (err error) {
+	if conn.stmts != nil && !conn.inTransaction {
+		conn.stmt, conn.releaseStmt, err = conn.stmts.get(ctx, conn.preparedSql)
+		return err
+	}
+	if conn.stmts != nil {
+		if stmt, release, ok := conn.stmts.lookup(conn.preparedSql); ok {
+			conn.rebindStmt(ctx, stmt, release)
+			return nil
+		}
+	}
+
+	//create new statement pointer
+	if conn.inTransaction {
+		conn.stmt, err = conn.tx.PrepareContext(ctx, conn.preparedSql)
+	} else {
+		conn.stmt, err = conn.db.PrepareContext(ctx, conn.preparedSql)
+	}
+	if err != nil {
+		return err
+	}
+	stmt := conn.stmt
+	conn.releaseStmt = func() { _ = stmt.Close() }
+	return nil
+}
+
+//rebind the cached statement to the transaction.
+func (conn *Conn) rebindStmt(ctx context.Context, stmt *sql.Stmt, release func()) {
+	txStmt := conn.tx.StmtContext(ctx, stmt)
+	conn.stmt = txStmt
+	conn.releaseStmt = func() {
+		_ = txStmt.Close()
+		release()
+	}
+}
+
 func buildResultMap(rows *sql.Rows, getFirst bool) (result []map[string]interface{}, err error) {
 	defer rows.Close()
 	var (
@@ -340,58 +715,50 @@ func buildResultMap(rows *sql.Rows, getFirst bool) (result []map[string]interfac
 		return
 	}
 
-	size := len(columnsProp)
 	for rows.Next() {
-		columns := make([]interface{}, size)
-		columnPointers := make([]interface{}, size)
-		for i := range columns {
-			columnPointers[i] = &columns[i]
-		}
-
-		// Scan the result into the column pointers...
-		if err := rows.Scan(columnPointers...); err != nil {
+		var m map[string]interface{}
+		if m, err = scanMap(rows, columnsProp); err != nil {
 			return nil, err
 		}
-		m := make(map[string]interface{})
-		for i, columnType := range columnsProp {
-			val := columnPointers[i].(*interface{})
-			databaseType := columnType.DatabaseTypeName()
-			colName := columnType.Name()
-
-			switch databaseType {
-			case "VARCHAR":
-				bv := (*val).([]byte)
-				m[colName] = string(bv[:])
-
-			case "CHAR":
-				bv := (*val).([]byte)
-				m[colName] = string(bv[:])
-
-			case "TINYINT":
-				m[colName] = int8((*val).(int64))
-
-			case "SMALLINT":
-				m[colName] = int16((*val).(int64))
-
-			case "TEXT":
-				bv := (*val).([]byte)
-				m[colName] = string(bv[:])
-			default:
-				m[colName] = *val
-			}
-
-		}
 		result = append(result, m)
 		if getFirst {
 			return
 		}
 	}
-	return
+	// the rows are closed when the context is canceled in reading
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scan the current row into a map.
+func scanMap(rows *sql.Rows, columnsProp []*sql.ColumnType) (map[string]interface{}, error) {
+	size := len(columnsProp)
+	columns := make([]interface{}, size)
+	columnPointers := make([]interface{}, size)
+	for i := range columns {
+		columnPointers[i] = &columns[i]
+	}
+
+	// Scan the result into the column pointers...
+	if err := rows.Scan(columnPointers...); err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	for i, columnType := range columnsProp {
+		val, err := convertValue(columns[i], columnType)
+		if err != nil {
+			return nil, fmt.Errorf("convert column %q failed: %v", columnType.Name(), err)
+		}
+		m[columnType.Name()] = val
+	}
+	return m, nil
 }
 
 // from https://github.com/blockloop/scan
 // reflect struct
-func fillRows(v interface{}, rows *sql.Rows) error {
+func fillRows(v interface{}, rows *sql.Rows, strict bool) error {
 	defer rows.Close()
 
 	vType := reflect.TypeOf(v)
@@ -411,27 +778,13 @@ func fillRows(v interface{}, rows *sql.Rows) error {
 		return err
 	}
 
-	isPrimitive := itemType.Kind() != reflect.Struct
+	if len(cols) == 0 {
+		return nil
+	}
 
 	for rows.Next() {
 		sliceItem := reflect.New(itemType).Elem()
-
-		var pointers []interface{}
-		if isPrimitive {
-			if len(cols) > 1 {
-				return ERR_TOOMANEYCOLUMNS
-			}
-			pointers = []interface{}{sliceItem.Addr().Interface()}
-		} else {
-			pointers = structPointers(sliceItem, cols, false)
-		}
-
-		if len(pointers) == 0 {
-			return nil
-		}
-
-		err := rows.Scan(pointers...)
-		if err != nil {
+		if err := scanValue(rows, cols, sliceItem, strict); err != nil {
 			return err
 		}
 		sliceVal.Set(reflect.Append(sliceVal, sliceItem))
@@ -439,45 +792,20 @@ func fillRows(v interface{}, rows *sql.Rows) error {
 	return rows.Err()
 }
 
-func structPointers(stct reflect.Value, cols []string, strict bool) []interface{} {
-	pointers := make([]interface{}, 0, len(cols))
-
-	fieldTag := initFieldTag(stct, len(cols))
-	for _, colName := range cols {
-		var fieldVal reflect.Value
-		if v, ok := fieldTag[colName]; ok {
-			fieldVal = v
-		} else {
-			if strict {
-				fieldVal = reflect.ValueOf(nil)
-			} else {
-				fieldVal = stct.FieldByName(strings.Title(colName))
-			}
+// scan the current row into item which is a struct or a primitive value.
+func scanValue(rows *sql.Rows, cols []string, item reflect.Value, strict bool) error {
+	var pointers []interface{}
+	if item.Kind() != reflect.Struct {
+		if len(cols) > 1 {
+			return ERR_TOOMANEYCOLUMNS
 		}
-		//fieldVal := fieldByName(stct, colName, strict)
-		if !fieldVal.IsValid() || !fieldVal.CanSet() {
-			// have to add if we found a column because Scan() requires
-			// len(cols) arguments or it will error. This way we can scan to
-			// a useless pointer
-			var nothing interface{}
-			pointers = append(pointers, &nothing)
-			continue
+		pointers = []interface{}{item.Addr().Interface()}
+	} else {
+		var err error
+		if pointers, err = structPointers(item, cols, strict); err != nil {
+			return err
 		}
-
-		pointers = append(pointers, fieldVal.Addr().Interface())
 	}
-	return pointers
+	return rows.Scan(pointers...)
 }
 
-// Initialization the tags from struct.
-func initFieldTag(v reflect.Value, len int) map[string]reflect.Value {
-	fieldTagMap := make(map[string]reflect.Value, len)
-	typ := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		tag, ok := typ.Field(i).Tag.Lookup("db")
-		if ok && tag != "" {
-			fieldTagMap[tag] = v.Field(i)
-		}
-	}
-	return fieldTagMap
-}
@@ -0,0 +1,106 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-08-02
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/AbelZhou/even/database"
+	"io"
+	"testing"
+	"time"
+)
+
+// slowDriver connects and returns rows slowly and calls onRow before every row,the error of onRow breaks the reading.
+type slowDriver struct{}
+
+type slowConn struct{}
+
+type slowStmt struct{}
+
+type slowRows struct {
+	n int
+}
+
+var onRow func(n int) error
+
+func init() {
+	sql.Register("even_test_slow", slowDriver{})
+}
+
+func (slowDriver) Open(name string) (driver.Conn, error) {
+	time.Sleep(time.Millisecond)
+	return slowConn{}, nil
+}
+
+func (slowConn) Prepare(query string) (driver.Stmt, error) { return slowStmt{}, nil }
+func (slowConn) Close() error                              { return nil }
+func (slowConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (slowStmt) Close() error                                    { return nil }
+func (slowStmt) NumInput() int                                   { return -1 }
+func (slowStmt) Exec(args []driver.Value) (driver.Result, error) { return driver.ResultNoRows, nil }
+func (slowStmt) Query(args []driver.Value) (driver.Rows, error)  { return &slowRows{}, nil }
+
+func (r *slowRows) Columns() []string { return []string{"id"} }
+func (r *slowRows) Close() error      { return nil }
+func (r *slowRows) Next(dest []driver.Value) error {
+	if r.n >= 10 {
+		return io.EOF
+	}
+	if onRow != nil {
+		if err := onRow(r.n); err != nil {
+			return err
+		}
+	}
+	dest[0] = int64(r.n)
+	r.n++
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func TestFetchAll_InterruptedInReading(t *testing.T) {
+	pool := NewPool(&database.Config{
+		Write: &database.DBConfig{DSN: "slow"},
+		Read:  []*database.DBConfig{{DSN: "slow"}},
+	}, "even_test_slow")
+	defer func() { onRow = nil }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	onRow = func(n int) error {
+		if n == 2 {
+			cancel()
+		}
+		return nil
+	}
+	res, err := pool.Master().Prepared("SELECT id FROM slow").FetchAllContext(ctx)
+	if err != ERR_CANCELED || res != nil {
+		t.Fatalf("The canceled query must return an error without partial rows.got:%d rows,%v", len(res), err)
+	}
+
+	onRow = nil
+	res, err = pool.Master().Timeout(25 * time.Millisecond).Prepared("SELECT id FROM slow").FetchAll()
+	if err != ERR_TIMEOUT || res != nil {
+		t.Fatalf("The query timed out must return an error without partial rows.got:%d rows,%v", len(res), err)
+	}
+
+	// the connection is broken in reading
+	onRow = func(n int) error {
+		if n == 3 {
+			return driver.ErrBadConn
+		}
+		return nil
+	}
+	if res, err = pool.Master().Prepared("SELECT id FROM slow").FetchAll(); err == nil || res != nil {
+		t.Fatalf("The broken reading must return an error without partial rows.got:%d rows,%v", len(res), err)
+	}
+
+	onRow = nil
+	if res, err = pool.Master().Prepared("SELECT id FROM slow").FetchAll(); err != nil || len(res) != 10 {
+		t.Errorf("The query must read all rows.got:%d rows,%v", len(res), err)
+	}
+}
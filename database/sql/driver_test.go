@@ -0,0 +1,186 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-08-05
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/AbelZhou/even/database"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// scriptDriver answers every statement by onStatement and records it in statements.
+// BEGIN,COMMIT and ROLLBACK are answered and recorded as statements too.
+// The database with DSN "down" can not be connected.
+type scriptDriver struct{}
+
+type scriptConn struct{}
+
+type scriptStmt struct {
+	query string
+}
+
+type scriptTx struct{}
+
+// every statement inserts or affects one row.
+type scriptExecResult struct{}
+
+type scriptRows struct {
+	*scriptResult
+	n int
+}
+
+// the result of a statement.
+// The columns are typed by types and scanTypes when they are set.
+type scriptResult struct {
+	columns   []string
+	types     []string
+	scanTypes []reflect.Type
+	rows      [][]driver.Value
+}
+
+var (
+	scriptMu    sync.Mutex
+	onStatement func(query string, args []driver.Value) (*scriptResult, error)
+	statements  []string
+	downOpens   int32
+)
+
+func init() {
+	sql.Register("even_test_script", scriptDriver{})
+}
+
+// set the script and clear the recorded statements.
+func resetScript(fn func(query string, args []driver.Value) (*scriptResult, error)) {
+	scriptMu.Lock()
+	defer scriptMu.Unlock()
+	onStatement = fn
+	statements = nil
+}
+
+// get the recorded statements.
+func recordedStatements() []string {
+	scriptMu.Lock()
+	defer scriptMu.Unlock()
+	return append([]string(nil), statements...)
+}
+
+func runScript(query string, args []driver.Value) (*scriptResult, error) {
+	scriptMu.Lock()
+	statements = append(statements, query)
+	fn := onStatement
+	scriptMu.Unlock()
+	if fn == nil {
+		return &scriptResult{}, nil
+	}
+	return fn(query, args)
+}
+
+func (scriptDriver) Open(name string) (driver.Conn, error) {
+	if name == "down" {
+		atomic.AddInt32(&downOpens, 1)
+		return nil, errors.New("connection refused")
+	}
+	return scriptConn{}, nil
+}
+
+func (scriptConn) Prepare(query string) (driver.Stmt, error) { return scriptStmt{query: query}, nil }
+func (scriptConn) Close() error                              { return nil }
+func (scriptConn) Begin() (driver.Tx, error) {
+	if _, err := runScript("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return scriptTx{}, nil
+}
+
+// BEGIN with the options,eg: BEGIN Repeatable Read READ ONLY
+func (scriptConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	query := "BEGIN"
+	if sql.IsolationLevel(opts.Isolation) != sql.LevelDefault {
+		query += " " + sql.IsolationLevel(opts.Isolation).String()
+	}
+	if opts.ReadOnly {
+		query += " READ ONLY"
+	}
+	if _, err := runScript(query, nil); err != nil {
+		return nil, err
+	}
+	return scriptTx{}, nil
+}
+
+func (scriptTx) Commit() error {
+	_, err := runScript("COMMIT", nil)
+	return err
+}
+
+func (scriptTx) Rollback() error {
+	_, err := runScript("ROLLBACK", nil)
+	return err
+}
+
+func (scriptExecResult) LastInsertId() (int64, error) { return 1, nil }
+func (scriptExecResult) RowsAffected() (int64, error) { return 1, nil }
+
+func (scriptStmt) Close() error  { return nil }
+func (scriptStmt) NumInput() int { return -1 }
+func (s scriptStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := runScript(s.query, args); err != nil {
+		return nil, err
+	}
+	return scriptExecResult{}, nil
+}
+func (s scriptStmt) Query(args []driver.Value) (driver.Rows, error) {
+	res, err := runScript(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptRows{scriptResult: res}, nil
+}
+
+func (r *scriptRows) Columns() []string { return r.columns }
+func (r *scriptRows) Close() error      { return nil }
+func (r *scriptRows) Next(dest []driver.Value) error {
+	if r.n >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.n])
+	r.n++
+	return nil
+}
+
+func (r *scriptRows) ColumnTypeDatabaseTypeName(i int) string {
+	if i < len(r.types) {
+		return r.types[i]
+	}
+	return ""
+}
+
+func (r *scriptRows) ColumnTypeScanType(i int) reflect.Type {
+	if i < len(r.scanTypes) {
+		return r.scanTypes[i]
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+// open a pool whose writer and reader are answered by the script.
+func openScriptPool(t *testing.T, options PoolOptions) *ConnPool {
+	t.Helper()
+	options.Lazy = true
+	pool, err := OpenPool(&database.Config{
+		Write: &database.DBConfig{DSN: "writer"},
+		Read:  []*database.DBConfig{{DSN: "reader"}},
+	}, "even_test_script", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
@@ -17,4 +17,32 @@ var ERR_MUSTBESLICE = errors.New("Must be a slice.")
 
 var ERR_MUSTBEPOINTER = errors.New("Must be a pointer.")
 
-var ERR_READERTRANSACTION = errors.New("The transaction must in a writer connection.")
\ No newline at end of file
+var ERR_READERTRANSACTION = errors.New("The transaction must in a writer connection unless it's read only.")
+
+var ERR_TIMEOUT = errors.New("The query was canceled by timeout.")
+
+var ERR_CANCELED = errors.New("The query was canceled.")
+
+var ERR_REPLICATIONLAG = errors.New("The replication lag exceeds the limit.")
+
+var ERR_REPLICATIONSTOPPED = errors.New("The replication is not running.")
+
+var ERR_NOWRITER = errors.New("The writer config is required.")
+
+var ERR_NOCOLUMNS = errors.New("The columns are required.")
+
+var ERR_UNSUPPORTEDVALUE = errors.New("Unsupported value of the database type.")
+
+var ERR_MIXEDPLACEHOLDERS = errors.New("The named and positional placeholders can not be mixed.")
+
+var ERR_NOSHARDS = errors.New("The shards are required.")
+
+var ERR_SHARDKEY = errors.New("Unsupported shard key.")
+
+var ERR_SHARDNOTFOUND = errors.New("The shard of key is not found.")
+
+var ERR_MANAGERCLOSED = errors.New("The manager was closed.")
+
+var ERR_POOLCLOSED = errors.New("The pool was closed.")
+
+var ERR_NOTWATCHABLE = errors.New("The config loader can not be watched.")
@@ -0,0 +1,186 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-01
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/go-sql-driver/mysql"
+	"strconv"
+	"time"
+)
+
+const (
+	errParse          = 1064 //the statement is unsupported by the server
+	errSpecificAccess = 1227 //eg: the REPLICATION CLIENT privilege is required
+)
+
+// HealthCheck is the config of reader health checker.
+type HealthCheck struct {
+	Interval time.Duration //check interval
+	Timeout  time.Duration //timeout of every check
+	MaxLag   time.Duration //max replication lag,0 means no lag check
+}
+
+// ReaderStatus is the result of the latest check on a reader.
+// The reader is healthy when Err is nil.
+type ReaderStatus struct {
+	Lag        time.Duration
+	LagUnknown bool //the replica status can not be read,the reader is kept healthy
+	Err        error
+	CheckedAt  time.Time
+}
+
+// start the background health checker of readers.
+// The unhealthy readers are ejected from Slave() until they recover.
+func (pool *ConnPool) StartHealthCheck(check HealthCheck) {
+	pool.StopHealthCheck()
+	if check.Interval <= 0 {
+		check.Interval = 5 * time.Second
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = time.Second
+	}
+
+	stop := make(chan struct{})
+	pool.mu.Lock()
+	pool.stopCheck = stop
+	pool.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(check.Interval)
+		defer ticker.Stop()
+		for {
+			pool.checkReaders(check)
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stop the health checker.
+func (pool *ConnPool) StopHealthCheck() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if pool.stopCheck != nil {
+		close(pool.stopCheck)
+		pool.stopCheck = nil
+	}
+}
+
+// get the status of readers.
+func (pool *ConnPool) ReaderStatus() []ReaderStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	status := make([]ReaderStatus, len(pool.readerStatus))
+	copy(status, pool.readerStatus)
+	return status
+}
+
+// check all the readers and update their status.
+func (pool *ConnPool) checkReaders(check HealthCheck) {
+	pool.mu.RLock()
+	readers := make([]*sql.DB, len(pool.reader))
+	copy(readers, pool.reader)
+	pool.mu.RUnlock()
+
+	status := make([]ReaderStatus, len(readers))
+	for i, reader := range readers {
+		status[i] = checkReader(reader, check)
+	}
+
+	pool.mu.Lock()
+	// readers may be changed while checking,update the unchanged ones only.
+	for i, reader := range readers {
+		if i < len(pool.reader) && pool.reader[i] == reader {
+			pool.readerStatus[i] = status[i]
+		}
+	}
+	pool.mu.Unlock()
+}
+
+func checkReader(db *sql.DB, check HealthCheck) ReaderStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), check.Timeout)
+	defer cancel()
+
+	status := ReaderStatus{CheckedAt: time.Now()}
+	if err := db.PingContext(ctx); err != nil {
+		status.Err = contextErr(ctx, err)
+		return status
+	}
+	if check.MaxLag <= 0 {
+		return status
+	}
+
+	status.Lag, status.Err = replicationLag(ctx, db)
+	if isLagUnknownErr(status.Err) {
+		status.Lag, status.LagUnknown, status.Err = 0, true, nil
+	}
+	if status.Err == nil && status.Lag > check.MaxLag {
+		status.Err = ERR_REPLICATIONLAG
+	}
+	return status
+}
+
+// get the replication lag by SHOW REPLICA STATUS,
+// SHOW SLAVE STATUS is used on the server before MySQL 8.0.22 and it's removed in MySQL 8.4.
+// The database which is not a replica has no lag.
+func replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	lag, err := replicaStatusLag(ctx, db, "SHOW REPLICA STATUS", "Seconds_Behind_Source")
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == errParse {
+		lag, err = replicaStatusLag(ctx, db, "SHOW SLAVE STATUS", "Seconds_Behind_Master")
+	}
+	return lag, err
+}
+
+// the lag can not be read when the statement is unsupported or the privilege is missing.
+func isLagUnknownErr(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && (mysqlErr.Number == errParse || mysqlErr.Number == errSpecificAccess)
+}
+
+func replicaStatusLag(ctx context.Context, db *sql.DB, query string, lagColumn string) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, contextErr(ctx, err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	values := make([]sql.RawBytes, len(cols))
+	pointers := make([]interface{}, len(cols))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return 0, err
+	}
+
+	for i, col := range cols {
+		if col != lagColumn {
+			continue
+		}
+		// NULL means the replication is not running.
+		if values[i] == nil {
+			return 0, ERR_REPLICATIONSTOPPED
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,95 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-01
+ */
+package sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"github.com/AbelZhou/even/database"
+	"github.com/go-sql-driver/mysql"
+	"testing"
+	"time"
+)
+
+func TestConnPool_PickReader(t *testing.T) {
+	writer, _ := sql.Open("even_mysql", "abel:123456@tcp(127.0.0.1:3306)/test")
+	reader0, _ := sql.Open("even_mysql", "abel:123456@tcp(127.0.0.1:3307)/test")
+	reader1, _ := sql.Open("even_mysql", "abel:123456@tcp(127.0.0.1:3308)/test")
+	pool := &ConnPool{
+		dbConfig:     &database.Config{Read: []*database.DBConfig{{}, {}}},
+		writer:       writer,
+		reader:       []*sql.DB{reader0, reader1},
+		readerStatus: make([]ReaderStatus, 2),
+	}
+
+	pool.readerStatus[0].Err = ERR_REPLICATIONLAG
+	for i := 0; i < 10; i++ {
+		if db := pool.Slave().db; db != reader1 {
+			t.Fatal("The unhealthy reader must be ejected.")
+		}
+	}
+
+	pool.readerStatus[1].Err = ERR_REPLICATIONSTOPPED
+	if conn := pool.Slave(); conn.db != writer || !conn.isReader {
+		t.Fatal("The writer must be used when no reader is healthy.")
+	}
+
+	pool.readerStatus[0].Err = nil
+	if db := pool.Slave().db; db != reader0 {
+		t.Fatal("The recovered reader must be reinstated.")
+	}
+}
+
+func TestCheckReader_ReplicationLag(t *testing.T) {
+	db, _ := sql.Open("even_test_script", "replica")
+	defer db.Close()
+	defer resetScript(nil)
+	check := HealthCheck{Timeout: time.Second, MaxLag: 10 * time.Second}
+	replicaStatus := func(column string, lag driver.Value) *scriptResult {
+		return &scriptResult{columns: []string{"Replica_IO_Running", column}, rows: [][]driver.Value{{[]byte("Yes"), lag}}}
+	}
+	cases := []struct {
+		replica, slave func() (*scriptResult, error)
+		lag            time.Duration
+		lagUnknown     bool
+		err            error
+	}{
+		// MySQL 8.0.22+
+		{replica: func() (*scriptResult, error) { return replicaStatus("Seconds_Behind_Source", []byte("3")), nil }, lag: 3 * time.Second},
+		{replica: func() (*scriptResult, error) { return replicaStatus("Seconds_Behind_Source", []byte("11")), nil }, lag: 11 * time.Second, err: ERR_REPLICATIONLAG},
+		{replica: func() (*scriptResult, error) { return replicaStatus("Seconds_Behind_Source", nil), nil }, err: ERR_REPLICATIONSTOPPED},
+		// not a replica
+		{replica: func() (*scriptResult, error) { return &scriptResult{}, nil }},
+		// before MySQL 8.0.22
+		{
+			replica: func() (*scriptResult, error) { return nil, &mysql.MySQLError{Number: errParse} },
+			slave:   func() (*scriptResult, error) { return replicaStatus("Seconds_Behind_Master", []byte("2")), nil },
+			lag:     2 * time.Second,
+		},
+		// without the REPLICATION CLIENT privilege
+		{replica: func() (*scriptResult, error) { return nil, &mysql.MySQLError{Number: errSpecificAccess} }, lagUnknown: true},
+		{
+			replica:    func() (*scriptResult, error) { return nil, &mysql.MySQLError{Number: errParse} },
+			slave:      func() (*scriptResult, error) { return nil, &mysql.MySQLError{Number: errParse} },
+			lagUnknown: true,
+		},
+	}
+	for i, c := range cases {
+		resetScript(func(query string, args []driver.Value) (*scriptResult, error) {
+			switch query {
+			case "SHOW REPLICA STATUS":
+				return c.replica()
+			case "SHOW SLAVE STATUS":
+				return c.slave()
+			}
+			return &scriptResult{}, nil
+		})
+		status := checkReader(db, check)
+		if status.Lag != c.lag || status.LagUnknown != c.lagUnknown || status.Err != c.err {
+			t.Errorf("case %d mismatch.got:%+v", i, status)
+		}
+	}
+}
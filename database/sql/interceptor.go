@@ -0,0 +1,87 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-06-24
+ */
+package sql
+
+import (
+	"context"
+	"time"
+)
+
+// operations of Event
+const (
+	OpQuery    = "query"
+	OpExecute  = "execute"
+	OpBegin    = "begin"
+	OpCommit   = "commit"
+	OpRollback = "rollback"
+)
+
+// Event describes one operation on a Conn.
+// SQL and Args can be rewritten in Interceptor.Before.
+type Event struct {
+	Op            string
+	SQL           string
+	Args          []interface{}
+	IsReader      bool
+	InTransaction bool
+	Start         time.Time
+	Duration      time.Duration
+	RowCount      int64 //rows returned by query or rows affected by execute
+	LastInsertID  int64
+	Cached        bool //the result came from cache
+	Err           error
+
+	entered int //count of interceptors whose Before has been called
+}
+
+// Interceptor hooks every operation on the Conns of a pool.
+// eg: logging, metrics, tracing, sql rewriting and auditing.
+//
+// Before is called in the registered order before the operation,the returned context
+// is used by the operation and passed to the next interceptor.
+// Returning an error aborts the operation(except rollback).
+//
+// After is called in the reverse order with the result of operation,it's also called
+// when the operation is aborted by a later interceptor.
+type Interceptor interface {
+	Before(ctx context.Context, event *Event) (context.Context, error)
+	After(ctx context.Context, event *Event)
+}
+
+// call Before of interceptors and apply the rewritten sql.
+func (conn *Conn) intercept(ctx context.Context, op string) (context.Context, *Event, error) {
+	event := &Event{
+		Op:            op,
+		SQL:           conn.preparedSql,
+		Args:          conn.args,
+		IsReader:      conn.isReader,
+		InTransaction: conn.inTransaction,
+		Start:         time.Now(),
+	}
+	if conn.prepareErr != nil {
+		return ctx, event, conn.prepareErr
+	}
+	for _, interceptor := range conn.interceptors {
+		var err error
+		ctx, err = interceptor.Before(ctx, event)
+		event.entered++
+		if err != nil {
+			return ctx, event, err
+		}
+	}
+	conn.preparedSql = event.SQL
+	conn.args = event.Args
+	return ctx, event, nil
+}
+
+// call After of interceptors with the result of operation.
+func (conn *Conn) complete(ctx context.Context, event *Event, err error) {
+	event.Duration = time.Since(event.Start)
+	event.Err = err
+	for i := event.entered - 1; i >= 0; i-- {
+		conn.interceptors[i].After(ctx, event)
+	}
+}
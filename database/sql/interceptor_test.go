@@ -0,0 +1,61 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-06-24
+ */
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordInterceptor struct {
+	name    string
+	calls   *[]string
+	err     error
+	rewrite string
+}
+
+func (r *recordInterceptor) Before(ctx context.Context, event *Event) (context.Context, error) {
+	*r.calls = append(*r.calls, "before:"+r.name+":"+event.Op)
+	if r.rewrite != "" {
+		event.SQL = r.rewrite
+	}
+	return ctx, r.err
+}
+
+func (r *recordInterceptor) After(ctx context.Context, event *Event) {
+	*r.calls = append(*r.calls, "after:"+r.name+":"+event.SQL)
+}
+
+func TestInterceptor_Chain(t *testing.T) {
+	var calls []string
+	errDenied := errors.New("denied")
+	conn := &Conn{interceptors: []Interceptor{
+		&recordInterceptor{name: "rewrite", calls: &calls, rewrite: "DELETE FROM `usertest` WHERE `id`=? LIMIT 1"},
+		&recordInterceptor{name: "audit", calls: &calls, err: errDenied},
+		&recordInterceptor{name: "never", calls: &calls},
+	}}
+
+	_, err := conn.Prepared("DELETE FROM `usertest` WHERE `id`=?", 1).AffectedCount()
+	if err != errDenied {
+		t.Fatalf("The error of interceptor must abort the operation.got:%v", err)
+	}
+
+	expect := []string{
+		"before:rewrite:execute",
+		"before:audit:execute",
+		"after:audit:DELETE FROM `usertest` WHERE `id`=? LIMIT 1",
+		"after:rewrite:DELETE FROM `usertest` WHERE `id`=? LIMIT 1",
+	}
+	if len(calls) != len(expect) {
+		t.Fatalf("Interceptor calls mismatch.expect:%v got:%v", expect, calls)
+	}
+	for i := range expect {
+		if calls[i] != expect[i] {
+			t.Errorf("Interceptor call %d mismatch.expect:%s got:%s", i, expect[i], calls[i])
+		}
+	}
+}
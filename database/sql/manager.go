@@ -0,0 +1,238 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-29
+ */
+package sql
+
+import (
+	"fmt"
+	"github.com/AbelZhou/even/database"
+	"sync"
+	"time"
+)
+
+// ConfigLoader loads the database config of dbtag,eg: *conf.Conf of register/conf.
+type ConfigLoader interface {
+	GetDBConf(dbtag string) *database.Config
+}
+
+// ConfigWatcher notifies the dbtag whose config is changed,eg: *conf.Conf of register/conf.
+type ConfigWatcher interface {
+	WatchDBConf(callback func(dbtag string)) (stop func(), err error)
+}
+
+// the changes of a dbtag in reloadDelay are reloaded once.
+const reloadDelay = time.Second
+
+// Manager creates a ConnPool for every dbtag on first use and caches it.
+type Manager struct {
+	loader     ConfigLoader
+	driverName string
+	options    PoolOptions
+	onOpen     func(dbtag string, pool *ConnPool) error
+	onReload   func(dbtag string, err error)
+	loadMu     sync.Mutex
+	mu         sync.Mutex
+	pools      map[string]*managedPool
+	reloads    map[string]*time.Timer
+	stopWatch  func()
+	closed     bool
+}
+
+// the pool is ready when ready is closed.
+type managedPool struct {
+	ready chan struct{}
+	pool  *ConnPool
+	err   error
+}
+
+func NewMySQLManager(loader ConfigLoader, options PoolOptions) *Manager {
+	return NewManager(loader, "even_mysql", options)
+}
+
+func NewManager(loader ConfigLoader, driverName string, options PoolOptions) *Manager {
+	return &Manager{
+		loader:     loader,
+		driverName: driverName,
+		options:    options,
+		pools:      make(map[string]*managedPool),
+		reloads:    make(map[string]*time.Timer),
+	}
+}
+
+// set up the pool after it's opened,eg: SetCache,Use and SetBalancer.
+// The pool is closed when fn returns an error.
+// It should be called before the manager is used.
+func (m *Manager) OnOpen(fn func(dbtag string, pool *ConnPool) error) {
+	m.onOpen = fn
+}
+
+// fn is called after the pool of dbtag is reloaded by Watch.
+// It should be called before Watch.
+func (m *Manager) OnReload(fn func(dbtag string, err error)) {
+	m.onReload = fn
+}
+
+// get the pool of dbtag,it's opened on first use.
+// The failed pool is not cached,it will be opened again on next call.
+func (m *Manager) Get(dbtag string) (*ConnPool, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ERR_MANAGERCLOSED
+	}
+	entry, ok := m.pools[dbtag]
+	if ok {
+		m.mu.Unlock()
+		<-entry.ready
+		return entry.pool, entry.err
+	}
+	entry = &managedPool{ready: make(chan struct{})}
+	m.pools[dbtag] = entry
+	m.mu.Unlock()
+
+	entry.pool, entry.err = m.open(dbtag)
+	close(entry.ready)
+	if entry.err != nil {
+		m.mu.Lock()
+		if m.pools[dbtag] == entry {
+			delete(m.pools, dbtag)
+		}
+		m.mu.Unlock()
+	}
+	return entry.pool, entry.err
+}
+
+// load the config of dbtag.
+// The loader is called one by one because the config driver may be not goroutine safe,eg: EtcdDriver.
+// The panic of config driver is returned as an error.
+func (m *Manager) load(dbtag string) (config *database.Config, err error) {
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("load the config of %q failed: %v", dbtag, r)
+		}
+	}()
+	config = m.loader.GetDBConf(dbtag)
+	if config == nil || config.Write == nil || config.Write.DSN == "" {
+		return nil, fmt.Errorf("the database %q is not configured", dbtag)
+	}
+	return config, nil
+}
+
+// load the config and open the pool.
+func (m *Manager) open(dbtag string) (pool *ConnPool, err error) {
+	config, err := m.load(dbtag)
+	if err != nil {
+		return nil, err
+	}
+	if pool, err = OpenPool(config, m.driverName, m.options); err != nil {
+		return nil, err
+	}
+	if m.onOpen != nil {
+		if err = m.onOpen(dbtag, pool); err != nil {
+			_ = pool.Close()
+			return nil, err
+		}
+	}
+	return pool, nil
+}
+
+// reload the config of dbtag and apply it to the opened pool.
+// It does nothing when the pool of dbtag is not opened.
+func (m *Manager) Reload(dbtag string) error {
+	m.mu.Lock()
+	entry, ok := m.pools[dbtag]
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	<-entry.ready
+	if entry.pool == nil {
+		return nil
+	}
+	config, err := m.load(dbtag)
+	if err != nil {
+		return err
+	}
+	return entry.pool.Reload(config)
+}
+
+// watch the config changes and reload the pools,the loader must be a ConfigWatcher.
+// The watch is stopped when the manager is closed.
+func (m *Manager) Watch() error {
+	watcher, ok := m.loader.(ConfigWatcher)
+	if !ok {
+		return ERR_NOTWATCHABLE
+	}
+	stop, err := watcher.WatchDBConf(m.scheduleReload)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		stop()
+		return ERR_MANAGERCLOSED
+	}
+	if m.stopWatch != nil {
+		m.stopWatch()
+	}
+	m.stopWatch = stop
+	return nil
+}
+
+// reload dbtag after reloadDelay,the changes in the delay are merged.
+func (m *Manager) scheduleReload(dbtag string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	if timer, ok := m.reloads[dbtag]; ok {
+		timer.Reset(reloadDelay)
+		return
+	}
+	m.reloads[dbtag] = time.AfterFunc(reloadDelay, func() {
+		m.mu.Lock()
+		delete(m.reloads, dbtag)
+		m.mu.Unlock()
+		err := m.Reload(dbtag)
+		if m.onReload != nil {
+			m.onReload(dbtag, err)
+		}
+	})
+}
+
+// close all the pools.The manager can not be used after closed.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	entries := m.pools
+	m.pools = make(map[string]*managedPool)
+	if m.stopWatch != nil {
+		m.stopWatch()
+	}
+	for _, timer := range m.reloads {
+		timer.Stop()
+	}
+	m.mu.Unlock()
+
+	var err error
+	for _, entry := range entries {
+		<-entry.ready
+		if entry.pool == nil {
+			continue
+		}
+		if pErr := entry.pool.Close(); pErr != nil && err == nil {
+			err = pErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-29
+ */
+package sql
+
+import (
+	"github.com/AbelZhou/even/database"
+	"sync"
+	"testing"
+)
+
+type mapLoader map[string]string
+
+func (l mapLoader) GetDBConf(dbtag string) *database.Config {
+	return &database.Config{Write: &database.DBConfig{DSN: l[dbtag]}}
+}
+
+// it's not goroutine safe like EtcdDriver which opens the client on read.
+type sharedLoader struct {
+	mapLoader
+	last string
+}
+
+func (l *sharedLoader) GetDBConf(dbtag string) *database.Config {
+	l.last = dbtag
+	return l.mapLoader.GetDBConf(l.last)
+}
+
+// run with -race
+func TestManager_ParallelGet(t *testing.T) {
+	loader := &sharedLoader{mapLoader: mapLoader{}}
+	tags := []string{"account", "order", "goods", "pay"}
+	for _, tag := range tags {
+		loader.mapLoader[tag] = "abel:123456@tcp(127.0.0.1:1)/" + tag
+	}
+	m := NewMySQLManager(loader, PoolOptions{Lazy: true})
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(tag string) {
+			defer wg.Done()
+			if _, err := m.Get(tag); err != nil {
+				t.Error(err)
+			}
+		}(tags[i%len(tags)])
+	}
+	wg.Wait()
+}
+
+func TestManager(t *testing.T) {
+	m := NewMySQLManager(mapLoader{"account": "abel:123456@tcp(127.0.0.1:1)/account"}, PoolOptions{Lazy: true})
+	opened := 0
+	m.OnOpen(func(dbtag string, pool *ConnPool) error {
+		opened++
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	pools := make([]*ConnPool, 10)
+	for i := range pools {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pools[i], _ = m.Get("account")
+		}(i)
+	}
+	wg.Wait()
+	for _, pool := range pools {
+		if pool == nil || pool != pools[0] {
+			t.Fatal("The pool of dbtag must be created once and cached.")
+		}
+	}
+	if opened != 1 {
+		t.Errorf("The pool must be set up once.got:%d", opened)
+	}
+
+	if _, err := m.Get("order"); err == nil {
+		t.Error("The unconfigured dbtag must return an error.")
+	}
+	if err := m.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := m.Get("account"); err != ERR_MANAGERCLOSED {
+		t.Errorf("The closed manager must return an error.got:%v", err)
+	}
+}
@@ -0,0 +1,154 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-23
+ */
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the struct metadata cached by type.
+var structMetas sync.Map
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// the mapping of columns to the fields of a struct type.
+// The column is matched by the db tag or the field name,ignoring case and underscores,
+// so create_time is mapped to CreateTime.
+type structMeta struct {
+	fields map[string]*fieldMeta //normalized name -> field
+}
+
+type fieldMeta struct {
+	index  []int //index path of the field,the embedded struct is traversed
+	tagged bool
+}
+
+// get the cached metadata of struct type.
+func getStructMeta(typ reflect.Type) *structMeta {
+	if meta, ok := structMetas.Load(typ); ok {
+		return meta.(*structMeta)
+	}
+	meta := &structMeta{fields: make(map[string]*fieldMeta)}
+	depths := make(map[string]int)
+	meta.walk(typ, nil, depths, map[reflect.Type]bool{typ: true})
+	actual, _ := structMetas.LoadOrStore(typ, meta)
+	return actual.(*structMeta)
+}
+
+// collect the fields of typ.The shallower field wins like Go,
+// and the tagged field wins in the same depth.
+func (meta *structMeta) walk(typ reflect.Type, index []int, depths map[string]int, visited map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := strings.Split(field.Tag.Get("db"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		path := make([]int, len(index)+1)
+		copy(path, index)
+		path[len(index)] = i
+
+		if field.Anonymous && tag == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if isEmbeddedStruct(field, embedded) && !visited[embedded] {
+				visited[embedded] = true
+				meta.walk(embedded, path, depths, visited)
+				delete(visited, embedded)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = field.Name
+		}
+		key := normalizeName(name)
+		depth, exists := depths[key]
+		if exists && (depth < len(path) || depth == len(path) && (meta.fields[key].tagged || tag == "")) {
+			continue
+		}
+		depths[key] = len(path)
+		meta.fields[key] = &fieldMeta{index: path, tagged: tag != ""}
+	}
+}
+
+// the embedded struct is traversed unless it's scanned as a value like time.Time.
+// The unexported embedded pointer can not be allocated.
+func isEmbeddedStruct(field reflect.StructField, embedded reflect.Type) bool {
+	if embedded.Kind() != reflect.Struct || embedded == timeType {
+		return false
+	}
+	if reflect.PtrTo(embedded).Implements(scannerType) {
+		return false
+	}
+	return field.Type.Kind() != reflect.Ptr || field.PkgPath == ""
+}
+
+// get the field of column.
+func (meta *structMeta) field(column string) (*fieldMeta, bool) {
+	f, ok := meta.fields[normalizeName(column)]
+	return f, ok
+}
+
+// lower case without underscores,create_time and CreateTime are the same.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
+
+// get the field by index path.
+// The nil embedded pointers are allocated when alloc,otherwise the invalid value is returned.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// get the scan pointers of the struct fields.
+// The unmapped column is scanned to a useless pointer,or returns an error when strict.
+func structPointers(stct reflect.Value, cols []string, strict bool) ([]interface{}, error) {
+	meta := getStructMeta(stct.Type())
+	pointers := make([]interface{}, 0, len(cols))
+	for _, colName := range cols {
+		f, ok := meta.field(colName)
+		if !ok {
+			if strict {
+				return nil, fmt.Errorf("column %q is not mapped to %s", colName, stct.Type().String())
+			}
+			// have to add if we found a column because Scan() requires
+			// len(cols) arguments or it will error. This way we can scan to
+			// a useless pointer
+			var nothing interface{}
+			pointers = append(pointers, &nothing)
+			continue
+		}
+		pointers = append(pointers, fieldByIndex(stct, f.index, true).Addr().Interface())
+	}
+	return pointers, nil
+}
@@ -0,0 +1,66 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-23
+ */
+package sql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mappingBase struct {
+	Id         int64
+	CreateTime time.Time
+}
+
+type MappingExtra struct {
+	Remark string
+}
+
+type mappingUser struct {
+	mappingBase
+	*MappingExtra
+	Id       int64 `db:"uid"`
+	Nickname *string
+	Mobile   sql.NullString
+	Password string `db:"-"`
+	secret   string
+}
+
+func TestStructPointers(t *testing.T) {
+	var user mappingUser
+	cols := []string{"uid", "id", "create_time", "nickname", "MOBILE", "remark", "password"}
+	pointers, err := structPointers(reflect.ValueOf(&user).Elem(), cols, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pointers[0] != &user.Id || pointers[1] != &user.mappingBase.Id || pointers[2] != &user.CreateTime ||
+		pointers[3] != &user.Nickname || pointers[4] != &user.Mobile {
+		t.Errorf("The fields are mapped incorrectly.")
+	}
+	if user.MappingExtra == nil || pointers[5] != &user.MappingExtra.Remark {
+		t.Errorf("The embedded pointer must be allocated.")
+	}
+	if _, ok := pointers[6].(*interface{}); !ok {
+		t.Errorf("The skipped field must not be mapped.")
+	}
+
+	if _, err = structPointers(reflect.ValueOf(&user).Elem(), []string{"secret"}, true); err == nil {
+		t.Errorf("The unmapped column must return an error in strict mode.")
+	}
+	if getStructMeta(reflect.ValueOf(&user).Elem().Type()) != getStructMeta(reflect.ValueOf(&user).Elem().Type()) {
+		t.Errorf("The struct metadata must be cached.")
+	}
+
+	values, err := batchValues([]mappingUser{{Id: 1}}, []string{"uid", "remark"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0][0] != int64(1) || values[0][1] != nil {
+		t.Errorf("Batch values of embedded struct mismatch.got:%v", values)
+	}
+}
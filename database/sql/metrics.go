@@ -0,0 +1,318 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-06-27
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/AbelZhou/even/database"
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Metrics exposes the prometheus metrics of pools,queries and caches.
+//
+// A Metrics can be shared by pools,they are distinguished by the "db" label.
+type Metrics struct {
+	registerer    prometheus.Registerer
+	namespace     string
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+	cacheOps      *prometheus.CounterVec
+}
+
+// create metrics and register them on the registerer.
+func NewMetrics(namespace string, registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		registerer: registerer,
+		namespace:  namespace,
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "sql",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of sql operations.",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"db", "target", "op", "statement"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sql",
+			Name:      "errors_total",
+			Help:      "Count of failed sql operations by error type.",
+		}, []string{"db", "target", "op", "type"}),
+		cacheOps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "operations_total",
+			Help:      "Count of cache operations by result.",
+		}, []string{"cache", "kind", "result"}),
+	}
+	for _, c := range []prometheus.Collector{m.queryDuration, m.queryErrors, m.cacheOps} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// wrap the cache to count hit,miss and set.
+// The table versions used by invalidation are counted with kind "version".
+func (m *Metrics) InstrumentCache(name string, cache database.Cache) database.Cache {
+	return &metricsCache{Cache: cache, name: name, ops: m.cacheOps}
+}
+
+// the interceptor of a pool.
+type poolMetrics struct {
+	metrics *Metrics
+	name    string
+}
+
+// collect the metrics of the pool named by name.
+func (pool *ConnPool) SetMetrics(m *Metrics, name string) error {
+	if err := m.registerer.Register(&poolCollector{pool: pool, name: name, namespace: m.namespace}); err != nil {
+		return err
+	}
+	pool.Use(&poolMetrics{metrics: m, name: name})
+	return nil
+}
+
+func (pm *poolMetrics) Before(ctx context.Context, event *Event) (context.Context, error) {
+	return ctx, nil
+}
+
+func (pm *poolMetrics) After(ctx context.Context, event *Event) {
+	target := "master"
+	if event.IsReader {
+		target = "slave"
+	}
+	if event.Cached {
+		target = "cache"
+	}
+	statement := statementName(ctx)
+	if statement == "" {
+		statement = fingerprint(event.SQL)
+	}
+	pm.metrics.queryDuration.WithLabelValues(pm.name, target, event.Op, statement).
+		Observe(event.Duration.Seconds())
+	if event.Err != nil {
+		pm.metrics.queryErrors.WithLabelValues(pm.name, target, event.Op, errorType(event.Err)).Inc()
+	}
+}
+
+type statementKey struct{}
+
+// name the statement of the calls with ctx in metrics,eg: WithStatement(ctx, "user.get").
+// Default the statement is the fingerprint of SQL.
+func WithStatement(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, statementKey{}, name)
+}
+
+func statementName(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	name, _ := ctx.Value(statementKey{}).(string)
+	return name
+}
+
+// the max length of statement fingerprint.
+const maxFingerprintLen = 200
+
+// get the fingerprint of sql which has bounded cardinality as the label.
+// The literals are replaced by ?,the lists like IN (?, ?) and VALUES (?), (?) are folded,
+// the index of sharded tables like user_07 is replaced by ?,and it's truncated to maxFingerprintLen.
+func fingerprint(query string) string {
+	runes := []rune(normalizeSQL(query))
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"':
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			out = append(out, '?')
+		case r == '`':
+			start := i + 1
+			for i++; i < len(runes) && runes[i] != '`'; i++ {
+			}
+			out = append(out, '`')
+			out = append(out, shardSuffix(runes[start:i])...)
+			out = append(out, '`')
+		case unicode.IsDigit(r) && (i == 0 || !isIdentRune(runes[i-1])):
+			for ; i+1 < len(runes) && (isIdentRune(runes[i+1]) || runes[i+1] == '.'); i++ {
+			}
+			out = append(out, '?')
+		case isIdentRune(r):
+			start := i
+			for ; i+1 < len(runes) && isIdentRune(runes[i+1]); i++ {
+			}
+			out = append(out, shardSuffix(runes[start:i+1])...)
+		case r == ',':
+			// "a ,b" and "a,b" are written as "a, b"
+			if len(out) > 0 && out[len(out)-1] == ' ' {
+				out = out[:len(out)-1]
+			}
+			out = append(out, ',', ' ')
+			if i+1 < len(runes) && runes[i+1] == ' ' {
+				i++
+			}
+		default:
+			out = append(out, r)
+		}
+	}
+
+	s := string(out)
+	for folded := ""; folded != s; {
+		folded = s
+		s = strings.Replace(s, "?, ?", "?", -1)
+		s = strings.Replace(s, "(?), (?)", "(?)", -1)
+	}
+	if runes = []rune(s); len(runes) > maxFingerprintLen {
+		s = string(runes[:maxFingerprintLen]) + "..."
+	}
+	return s
+}
+
+// replace the digits suffix of identifier by ?,eg: user_07 -> user_?
+func shardSuffix(ident []rune) []rune {
+	i := len(ident)
+	for i > 0 && unicode.IsDigit(ident[i-1]) {
+		i--
+	}
+	if i == len(ident) || i < 2 || ident[i-1] != '_' {
+		return ident
+	}
+	return append(ident[:i:i], '?')
+}
+
+// classify the error for the label.
+func errorType(err error) string {
+	switch err {
+	case ERR_TIMEOUT:
+		return "timeout"
+	case ERR_CANCELED:
+		return "canceled"
+	case driver.ErrBadConn, mysql.ErrInvalidConn:
+		return "bad_conn"
+	case sql.ErrTxDone:
+		return "tx_done"
+	}
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+		return "mysql_" + strconv.Itoa(int(mysqlErr.Number))
+	}
+	return "other"
+}
+
+// count the operations of cache.
+type metricsCache struct {
+	database.Cache
+	name string
+	ops  *prometheus.CounterVec
+}
+
+func (c *metricsCache) Get(key string) interface{} {
+	v := c.Cache.Get(key)
+	result := "hit"
+	if v == nil {
+		result = "miss"
+	}
+	c.ops.WithLabelValues(c.name, cacheKind(key), result).Inc()
+	return v
+}
+
+func (c *metricsCache) Set(key string, value interface{}) bool {
+	ok := c.Cache.Set(key, value)
+	c.ops.WithLabelValues(c.name, cacheKind(key), setResult(ok)).Inc()
+	return ok
+}
+
+func (c *metricsCache) SetWithExpire(key string, value interface{}, expire int32) bool {
+	ok := c.Cache.SetWithExpire(key, value, expire)
+	c.ops.WithLabelValues(c.name, cacheKind(key), setResult(ok)).Inc()
+	return ok
+}
+
+func cacheKind(key string) string {
+	if strings.HasPrefix(key, tableVersionPrefix) {
+		return "version"
+	}
+	return "result"
+}
+
+func setResult(ok bool) string {
+	if ok {
+		return "set"
+	}
+	return "set_failed"
+}
+
+// collect sql.DBStats of writer and readers.
+type poolCollector struct {
+	pool      *ConnPool
+	name      string
+	namespace string
+}
+
+// the metrics of sql.DBStats.
+var dbStatsDescs = []struct {
+	name      string
+	help      string
+	valueType prometheus.ValueType
+	value     func(stats sql.DBStats) float64
+}{
+	{"max_open_connections", "Maximum number of open connections.", prometheus.GaugeValue,
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{"open_connections", "Number of established connections both in use and idle.", prometheus.GaugeValue,
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{"in_use_connections", "Number of connections currently in use.", prometheus.GaugeValue,
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{"idle_connections", "Number of idle connections.", prometheus.GaugeValue,
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{"wait_count_total", "Total number of connections waited for.", prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{"wait_duration_seconds_total", "Total time blocked waiting for a new connection.", prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{"max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.", prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{"max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.", prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+func (c *poolCollector) desc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "sql", name), help,
+		[]string{"target", "index"}, prometheus.Labels{"db": c.name})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range dbStatsDescs {
+		ch <- c.desc(d.name, d.help)
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.pool.mu.RLock()
+	defer c.pool.mu.RUnlock()
+	c.collect(ch, "master", "0", c.pool.writer)
+	for i, reader := range c.pool.reader {
+		c.collect(ch, "slave", strconv.Itoa(i), reader)
+	}
+}
+
+func (c *poolCollector) collect(ch chan<- prometheus.Metric, target string, index string, db *sql.DB) {
+	if db == nil {
+		return
+	}
+	stats := db.Stats()
+	for _, d := range dbStatsDescs {
+		ch <- prometheus.MustNewConstMetric(c.desc(d.name, d.help), d.valueType, d.value(stats), target, index)
+	}
+}
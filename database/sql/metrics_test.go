@@ -0,0 +1,84 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-06-27
+ */
+package sql
+
+import (
+	"context"
+	"github.com/AbelZhou/even/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_Cache(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := NewMetrics("even", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gc := m.InstrumentCache("gcache", cache.NewGCache(100))
+	gc.Get("k")
+	gc.Set("k", "v")
+	gc.Get("k")
+	gc.Get(tableVersionPrefix + "usertest")
+
+	pm := &poolMetrics{metrics: m, name: "account"}
+	pm.After(context.Background(), &Event{Op: OpQuery, SQL: "SELECT 1", Duration: time.Millisecond, Err: ERR_TIMEOUT})
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				key += "|" + label.GetValue()
+			}
+			if metric.GetCounter() != nil {
+				counts[key] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+
+	expect := map[string]float64{
+		"even_cache_operations_total|gcache|result|hit":      1,
+		"even_cache_operations_total|gcache|result|miss":     1,
+		"even_cache_operations_total|gcache|result|set":      1,
+		"even_cache_operations_total|gcache|version|miss":    1,
+		"even_sql_errors_total|account|query|master|timeout": 1,
+	}
+	for key, v := range expect {
+		if counts[key] != v {
+			t.Errorf("Metric %s expect %v got %v.all:%v", key, v, counts[key], counts)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM users WHERE id IN (?, ?, ?) AND name='abel'":    "SELECT * FROM users WHERE id IN (?) AND name=?",
+		"SELECT * FROM users WHERE id IN (?,?) AND age > 18":           "SELECT * FROM users WHERE id IN (?) AND age > ?",
+		"INSERT INTO `users` (`a`, `b`) VALUES (?, ?), (?, ?), (?, ?)": "INSERT INTO `users` (`a`, `b`) VALUES (?)",
+		"SELECT user_07.id FROM `user_07`\n WHERE id = ?":              "SELECT user_?.id FROM `user_?` WHERE id = ?",
+		"SELECT id FROM log2019 WHERE t = 1.5e3":                       "SELECT id FROM log2019 WHERE t = ?",
+	}
+	for sql, expect := range cases {
+		if got := fingerprint(sql); got != expect {
+			t.Errorf("Fingerprint mismatch.sql:%s got:%s", sql, got)
+		}
+	}
+	if got := fingerprint("SELECT " + strings.Repeat("a, ", 200) + "b"); len([]rune(got)) != maxFingerprintLen+3 {
+		t.Errorf("The long fingerprint must be truncated.got:%d", len(got))
+	}
+	ctx := WithStatement(context.Background(), "user.get")
+	if statementName(ctx) != "user.get" || statementName(context.Background()) != "" {
+		t.Error("The statement name of context mismatch.")
+	}
+}
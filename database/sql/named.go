@@ -0,0 +1,173 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-24
+ */
+package sql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// bind the :name placeholders by a map or struct.
+// The query without named placeholders is returned as it is.
+func bindNamed(query string, args []interface{}) (string, []interface{}, error) {
+	if len(args) != 1 || !isNamedArg(args[0]) {
+		return query, args, nil
+	}
+	parts, names, positional := parseNamed(query)
+	if len(names) == 0 {
+		return query, args, nil
+	}
+	if positional {
+		return query, args, ERR_MIXEDPLACEHOLDERS
+	}
+
+	lookup := namedLookup(reflect.ValueOf(args[0]))
+	var builder strings.Builder
+	bound := make([]interface{}, 0, len(names))
+	for i, name := range names {
+		builder.WriteString(parts[i])
+		v, ok := lookup(name)
+		if !ok {
+			return query, args, fmt.Errorf("named parameter %q is not found", name)
+		}
+		values, expanded := ExpandSlice(v)
+		if !expanded {
+			builder.WriteString("?")
+			bound = append(bound, v)
+			continue
+		}
+		if len(values) == 0 {
+			return query, args, fmt.Errorf("named parameter %q is an empty slice", name)
+		}
+		builder.WriteString(strings.Repeat("?, ", len(values)-1) + "?")
+		bound = append(bound, values...)
+	}
+	builder.WriteString(parts[len(names)])
+	return builder.String(), bound, nil
+}
+
+// map with string key,struct or pointer to struct.
+func isNamedArg(arg interface{}) bool {
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+	typ := reflect.TypeOf(arg)
+	if typ == nil {
+		return false
+	}
+	if typ.Kind() == reflect.Ptr {
+		if reflect.ValueOf(arg).IsNil() {
+			return false
+		}
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Map:
+		return typ.Key().Kind() == reflect.String
+	case reflect.Struct:
+		return typ != timeType
+	}
+	return false
+}
+
+// get the value of name from the map or struct.
+func namedLookup(v reflect.Value) func(name string) (interface{}, bool) {
+	v = reflect.Indirect(v)
+	if v.Kind() == reflect.Map {
+		return func(name string) (interface{}, bool) {
+			value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !value.IsValid() {
+				return nil, false
+			}
+			return value.Interface(), true
+		}
+	}
+	meta := getStructMeta(v.Type())
+	return func(name string) (interface{}, bool) {
+		f, ok := meta.field(name)
+		if !ok {
+			return nil, false
+		}
+		// the field of nil embedded pointer is NULL
+		if field := fieldByIndex(v, f.index, false); field.IsValid() {
+			return field.Interface(), true
+		}
+		return nil, true
+	}
+}
+
+// ExpandSlice returns the elements of the slice arg for IN (:ids) or IN (?).
+// []byte and driver.Valuer are not expanded.
+func ExpandSlice(v interface{}) ([]interface{}, bool) {
+	if _, ok := v.(driver.Valuer); ok {
+		return nil, false
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, false
+	}
+	if val.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	values := make([]interface{}, val.Len())
+	for i := range values {
+		values[i] = val.Index(i).Interface()
+	}
+	return values, true
+}
+
+// split the query by the :name placeholders,len(parts) is len(names)+1.
+// The placeholders in quotes and comments are ignored,so are :: and :=.
+// positional is true when the query has ? placeholders.
+func parseNamed(query string) (parts []string, names []string, positional bool) {
+	runes := []rune(query)
+	start := 0
+	// skip to the end of quote or comment which is started at i
+	skip := func(i int, end string) int {
+		for i++; i < len(runes); i++ {
+			if runes[i] == '\\' && end != "`" && end != "\n" && end != "*/" {
+				i++
+				continue
+			}
+			if strings.HasPrefix(string(runes[i:]), end) {
+				return i + len(end) - 1
+			}
+		}
+		return len(runes)
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			i = skip(i, string(r))
+		case r == '#' || r == '-' && next == '-' && (i+2 == len(runes) || unicode.IsSpace(runes[i+2])):
+			i = skip(i, "\n")
+		case r == '/' && next == '*':
+			i = skip(i+1, "*/")
+		case r == '?':
+			positional = true
+		case r == ':' && next == ':':
+			i++
+		case r == ':' && (next == '_' || unicode.IsLetter(next)):
+			parts = append(parts, string(runes[start:i]))
+			j := i + 1
+			for ; j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])); j++ {
+			}
+			names = append(names, string(runes[i+1:j]))
+			start = j
+			i = j - 1
+		}
+	}
+	parts = append(parts, string(runes[start:]))
+	return parts, names, positional
+}
@@ -0,0 +1,47 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-24
+ */
+package sql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindNamed(t *testing.T) {
+	query, args, err := bindNamed(
+		"SELECT * FROM users WHERE id IN (:ids) AND nickname=:nickname AND note=':skip' -- :skip\n AND t::date AND @a:=1",
+		[]interface{}{map[string]interface{}{"ids": []int64{1, 2, 3}, "nickname": "abel"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "SELECT * FROM users WHERE id IN (?, ?, ?) AND nickname=? AND note=':skip' -- :skip\n AND t::date AND @a:=1"
+	if query != expect || !reflect.DeepEqual(args, []interface{}{int64(1), int64(2), int64(3), "abel"}) {
+		t.Errorf("Bind map mismatch.got:%s %v", query, args)
+	}
+
+	user := &Usertest{Id: 1, Nickname: "abel"}
+	query, args, err = bindNamed("UPDATE usertest SET nickname=:nickname WHERE id=:id", []interface{}{user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "UPDATE usertest SET nickname=? WHERE id=?" || !reflect.DeepEqual(args, []interface{}{"abel", user.Id}) {
+		t.Errorf("Bind struct mismatch.got:%s %v", query, args)
+	}
+
+	query, args, err = bindNamed("SELECT * FROM users WHERE id=?", []interface{}{1})
+	if err != nil || query != "SELECT * FROM users WHERE id=?" || len(args) != 1 {
+		t.Errorf("The positional args must not be changed.got:%s %v %v", query, args, err)
+	}
+	if _, _, err = bindNamed("SELECT :missing", []interface{}{map[string]interface{}{}}); err == nil {
+		t.Error("The missing named parameter must return an error.")
+	}
+	if _, _, err = bindNamed("SELECT :a, ?", []interface{}{map[string]interface{}{"a": 1}}); err != ERR_MIXEDPLACEHOLDERS {
+		t.Errorf("The mixed placeholders must return an error.got:%v", err)
+	}
+	if _, _, err = bindNamed("SELECT :ids", []interface{}{map[string]interface{}{"ids": []int{}}}); err == nil {
+		t.Error("The empty slice must return an error.")
+	}
+}
@@ -8,61 +8,235 @@ package sql
 import (
 	"database/sql"
 	"github.com/AbelZhou/even/database"
+	"github.com/go-sql-driver/mysql"
 	"math/rand"
+	"sync"
 	"time"
 )
 
+// PoolOptions is the startup options of OpenPool.
+type PoolOptions struct {
+	Retries    int           //retry times of ping on startup,negative means retrying until connected
+	Backoff    time.Duration //backoff before the first retry,doubled every retry.Default 100ms
+	MaxBackoff time.Duration //Default 5s
+	Lazy       bool          //do not ping on startup,connections are established on first use
+
+	StmtCacheSize int  //max prepared statements cached per database,0 means no cache
+	Interpolate   bool //interpolate args by driver instead of server side prepare(interpolateParams=true)
+
+	DrainDelay time.Duration //delay before closing the databases removed by Reload.Default 10s
+}
+
 func NewMySQLPool(config *database.Config) *ConnPool {
-	return NewPool(config, "even_mysql");
+	return NewPool(config, "even_mysql")
 }
 
+// It panics when the writer is unavailable.Use OpenPool to handle the error.
 func NewPool(config *database.Config, driverName string) *ConnPool {
+	pool, err := OpenPool(config, driverName, PoolOptions{})
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+func OpenMySQLPool(config *database.Config, options PoolOptions) (*ConnPool, error) {
+	return OpenPool(config, "even_mysql", options)
+}
+
+// create the pool and return the error when the writer is unavailable.
+// The unavailable readers are ejected,and reinstated once they can be connected.
+func OpenPool(config *database.Config, driverName string, options PoolOptions) (*ConnPool, error) {
+	if config.Write == nil {
+		return nil, ERR_NOWRITER
+	}
 	//format database config
 	configFormat(config)
 
-	if config.Read == nil {
-		config.Read[0] = config.Write
+	if len(config.Read) == 0 {
+		config.Read = []*database.DBConfig{config.Write}
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = 100 * time.Millisecond
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = 5 * time.Second
+	}
+	if options.DrainDelay <= 0 {
+		options.DrainDelay = 10 * time.Second
 	}
 
 	//load writer database connections
-	var writerConn, err = sql.Open(driverName, config.Write.DSN)
+	writerConn, err := openDB(driverName, config.Write, options)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if !options.Lazy {
+		if err = pingWithRetry(writerConn, options, nil); err != nil {
+			_ = writerConn.Close()
+			return nil, err
+		}
 	}
 
-	err = writerConn.Ping()
-	if err != nil {
-		panic(err)
+	pool := &ConnPool{
+		dbConfig:    config,
+		driverName:  driverName,
+		writer:      writerConn,
+		balancer:    NewRandomBalancer(),
+		closed:      make(chan struct{}),
+		options:     options,
+		stmtCaches:  make(map[*sql.DB]*stmtCache),
+		revives:     make(map[*sql.DB]chan struct{}),
+		interpolate: options.Interpolate,
 	}
-	writerConn.SetMaxOpenConns(config.Write.MaxActive)
-	writerConn.SetMaxIdleConns(config.Write.MaxIdle)
-	writerConn.SetConnMaxLifetime(time.Duration(config.Write.IdleTimeout) * time.Second)
+	pool.addStmtCache(writerConn)
 
 	// load reader database connections.
-	var readerConn []*sql.DB
 	for _, readerConf := range config.Read {
-		reader, err := sql.Open(driverName, readerConf.DSN)
+		reader, err := openDB(driverName, readerConf, options)
 		if err != nil {
-			panic(err)
+			_ = pool.Close()
+			return nil, err
+		}
+		pool.reader = append(pool.reader, reader)
+		pool.readerStatus = append(pool.readerStatus, ReaderStatus{})
+		pool.addStmtCache(reader)
+	}
+	if options.Lazy {
+		return pool, nil
+	}
+
+	for i, reader := range pool.reader {
+		if err := reader.Ping(); err != nil {
+			pool.readerStatus[i] = ReaderStatus{Err: err, CheckedAt: time.Now()}
+			go pool.reviveReader(reader, options, pool.watchReader(reader))
 		}
-		err = reader.Ping()
+	}
+	return pool, nil
+}
+
+// open the database and apply the pool config.
+func openDB(driverName string, conf *database.DBConfig, options PoolOptions) (*sql.DB, error) {
+	dsn := conf.DSN
+	if options.Interpolate {
+		mysqlConf, err := mysql.ParseDSN(dsn)
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+		mysqlConf.InterpolateParams = true
+		dsn = mysqlConf.FormatDSN()
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	resizeDB(db, conf)
+	return db, nil
+}
+
+// apply the pool size of config.
+func resizeDB(db *sql.DB, conf *database.DBConfig) {
+	db.SetMaxOpenConns(conf.MaxActive)
+	db.SetMaxIdleConns(conf.MaxIdle)
+	db.SetConnMaxLifetime(time.Duration(conf.IdleTimeout) * time.Second)
+}
+
+// ping with exponential backoff.
+// It stops when closed is closed.
+func pingWithRetry(db *sql.DB, options PoolOptions, closed chan struct{}) (err error) {
+	backoff := options.Backoff
+	for i := 0; ; i++ {
+		if err = db.Ping(); err == nil {
+			return nil
+		}
+		if options.Retries >= 0 && i >= options.Retries {
+			return err
+		}
+		select {
+		case <-closed:
+			return err
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > options.MaxBackoff {
+			backoff = options.MaxBackoff
+		}
+	}
+}
+
+// retry the unavailable reader until it's connected or stop is closed.
+func (pool *ConnPool) reviveReader(reader *sql.DB, options PoolOptions, stop chan struct{}) {
+	options.Retries = -1
+	if err := pingWithRetry(reader, options, stop); err != nil {
+		return
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if pool.revives[reader] != stop {
+		return
+	}
+	delete(pool.revives, reader)
+	for i := range pool.reader {
+		if pool.reader[i] == reader {
+			pool.readerStatus[i] = ReaderStatus{CheckedAt: time.Now()}
 		}
-		reader.SetConnMaxLifetime(time.Duration(readerConf.IdleTimeout) * time.Second)
-		reader.SetMaxIdleConns(readerConf.MaxIdle)
-		reader.SetMaxOpenConns(readerConf.MaxActive)
-		readerConn = append(readerConn, reader)
+	}
+}
+
+// register the stop channel of reviving reader.
+// It's closed when the reader is removed or the pool is closed.The caller must hold mu
+// or own the pool exclusively.
+func (pool *ConnPool) watchReader(reader *sql.DB) chan struct{} {
+	stop := make(chan struct{})
+	pool.revives[reader] = stop
+	return stop
+}
+
+// stop reviving the reader.The caller must hold mu.
+func (pool *ConnPool) unwatchReader(reader *sql.DB) {
+	if stop, ok := pool.revives[reader]; ok {
+		close(stop)
+		delete(pool.revives, reader)
+	}
+}
+
+// create the statement cache of db when it's enabled.
+func (pool *ConnPool) addStmtCache(db *sql.DB) {
+	if pool.options.StmtCacheSize > 0 {
+		pool.stmtCaches[db] = newStmtCache(db, pool.options.StmtCacheSize)
+	}
+}
+
+// close the writer and readers.
+// The pool can not be used after closed.
+func (pool *ConnPool) Close() error {
+	pool.StopHealthCheck()
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	select {
+	case <-pool.closed:
+		return nil
+	default:
+		close(pool.closed)
 	}
 
-	return &ConnPool{
-		dbConfig: config,
-		writer:   writerConn,
-		reader:   readerConn,
+	for reader := range pool.revives {
+		pool.unwatchReader(reader)
+	}
+	for _, stmts := range pool.stmtCaches {
+		stmts.close()
 	}
+	err := pool.writer.Close()
+	for _, reader := range pool.reader {
+		if rErr := reader.Close(); rErr != nil && err == nil {
+			err = rErr
+		}
+	}
+	return err
 }
 
-//Progress the database config.
+// Progress the database config.
 func configFormat(dbConfig *database.Config) {
 	if dbConfig.Write.MaxActive == 0 {
 		dbConfig.Write.MaxActive = dbConfig.DefMaxActive
@@ -88,23 +262,119 @@ func configFormat(dbConfig *database.Config) {
 }
 
 type ConnPool struct {
-	dbConfig *database.Config
-	writer   *sql.DB
-	reader   []*sql.DB
+	dbConfig     *database.Config //guarded by mu
+	driverName   string
+	writer       *sql.DB      //guarded by mu
+	mu           sync.RWMutex //guards reader and readerStatus
+	reader       []*sql.DB
+	readerStatus []ReaderStatus
+	stopCheck    chan struct{}
+	closed       chan struct{}
+	balancer     Balancer
+	options      PoolOptions
+	stmtCaches   map[*sql.DB]*stmtCache    //guarded by mu
+	revives      map[*sql.DB]chan struct{} //stop channels of reviving readers,guarded by mu
+	reloadMu     sync.Mutex                //serializes Reload
+	interpolate  bool
+	cache        database.Cache
+	cacheExpire  int32
+	interceptors []Interceptor
+	consistency  Consistency
+	strict       bool
+}
+
+// set the query result cache of the pool.
+// expire is the default expire of cached result.Second.0 means never expire.
+func (pool *ConnPool) SetCache(cache database.Cache, expire int32) {
+	pool.cache = cache
+	pool.cacheExpire = expire
+}
+
+// return an error when a column can not be mapped to the struct field in Scan.
+// Default the unmapped columns are ignored.
+func (pool *ConnPool) SetStrict(strict bool) {
+	pool.strict = strict
+}
+
+// register interceptors for all the Conns got from the pool.
+// It should be called before the pool is used.
+func (pool *ConnPool) Use(interceptors ...Interceptor) {
+	pool.interceptors = append(pool.interceptors, interceptors...)
+}
+
+// record the operations exceed the threshold of logger.
+func (pool *ConnPool) SetSlowLog(logger *SlowLogger) {
+	pool.Use(logger)
 }
 
 func (pool *ConnPool) Master() *Conn {
-	return &Conn{
-		db:            pool.writer,
-		inTransaction: false,
-		isReader:false,
-	}
+	return pool.newConn(pool.master(), false)
 }
 
+// get the writer database,it may be replaced by Reload.
+func (pool *ConnPool) master() *sql.DB {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	return pool.writer
+}
+
+// set the balancer of readers.
+// It should be called before the pool is used.
+func (pool *ConnPool) SetBalancer(balancer Balancer) {
+	pool.balancer = balancer
+}
+
+// get a reader connection from healthy readers.
+// The writer is used when no reader is healthy.
 func (pool *ConnPool) Slave() *Conn {
+	return pool.newConn(pool.pickReader(""), true)
+}
+
+// get a reader connection by key,it's used by the consistent hash balancer.
+func (pool *ConnPool) SlaveWithKey(key string) *Conn {
+	return pool.newConn(pool.pickReader(key), true)
+}
+
+func (pool *ConnPool) newConn(db *sql.DB, isReader bool) *Conn {
+	pool.mu.RLock()
+	stmts := pool.stmtCaches[db]
+	pool.mu.RUnlock()
+
 	return &Conn{
-		db:            pool.reader[rand.Intn(len(pool.reader))],
+		db:            db,
 		inTransaction: false,
-		isReader:true,
+		isReader:      isReader,
+		cache:         pool.cache,
+		cacheExpire:   pool.cacheExpire,
+		interceptors:  pool.interceptors,
+		consistency:   pool.consistency,
+		stmts:         stmts,
+		interpolate:   pool.interpolate,
+		strict:        pool.strict,
+	}
+}
+
+// pick a healthy reader by the balancer.
+func (pool *ConnPool) pickReader(key string) *sql.DB {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	healthy := make([]Replica, 0, len(pool.reader))
+	for i, reader := range pool.reader {
+		if pool.readerStatus[i].Err != nil {
+			continue
+		}
+		weight := 1
+		if i < len(pool.dbConfig.Read) && pool.dbConfig.Read[i].Weight > 0 {
+			weight = pool.dbConfig.Read[i].Weight
+		}
+		healthy = append(healthy, Replica{Index: i, DB: reader, Weight: weight})
+	}
+	if len(healthy) == 0 {
+		return pool.writer
+	}
+	if pool.balancer == nil {
+		return healthy[rand.Intn(len(healthy))].DB
 	}
+	return healthy[pool.balancer.Pick(healthy, key)].DB
 }
@@ -0,0 +1,32 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-08
+ */
+package sql
+
+import (
+	"github.com/AbelZhou/even/database"
+	"testing"
+	"time"
+)
+
+func TestOpenPool_Unavailable(t *testing.T) {
+	config := &database.Config{
+		Write: &database.DBConfig{DSN: "abel:123456@tcp(127.0.0.1:1)/test?timeout=100ms"},
+	}
+	if _, err := OpenMySQLPool(config, PoolOptions{Retries: 1, Backoff: time.Millisecond}); err == nil {
+		t.Fatal("The unavailable writer must return an error.")
+	}
+
+	pool, err := OpenMySQLPool(config, PoolOptions{Lazy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.reader) != 1 || pool.Slave().db != pool.reader[0] {
+		t.Error("The writer must be the reader when no reader is configured.")
+	}
+	if err = pool.Close(); err != nil {
+		t.Error(err)
+	}
+}
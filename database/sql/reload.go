@@ -0,0 +1,165 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-30
+ */
+package sql
+
+import (
+	"database/sql"
+	"github.com/AbelZhou/even/database"
+	"time"
+)
+
+// reload the pool by config without restart.
+// The databases with unchanged DSN are kept and resized,the added ones are opened,
+// and the removed ones are drained: closed after DrainDelay so the Conns got before can finish.
+// The pool is unchanged when the new writer is unavailable.
+// The concurrent reloads are applied one by one,or the databases opened by one of them are leaked.
+// It returns ERR_POOLCLOSED after the pool is closed.
+func (pool *ConnPool) Reload(config *database.Config) error {
+	pool.reloadMu.Lock()
+	defer pool.reloadMu.Unlock()
+	if pool.isClosed() {
+		return ERR_POOLCLOSED
+	}
+	if config.Write == nil {
+		return ERR_NOWRITER
+	}
+	configFormat(config)
+	if len(config.Read) == 0 {
+		config.Read = []*database.DBConfig{config.Write}
+	}
+
+	pool.mu.RLock()
+	oldConfig := pool.dbConfig
+	writer := pool.writer
+	oldReaders := make([]*sql.DB, len(pool.reader))
+	copy(oldReaders, pool.reader)
+	oldStatus := make([]ReaderStatus, len(pool.readerStatus))
+	copy(oldStatus, pool.readerStatus)
+	pool.mu.RUnlock()
+
+	var opened, removed []*sql.DB
+	closeOpened := func() {
+		for _, db := range opened {
+			_ = db.Close()
+		}
+	}
+
+	// writer
+	if writer == nil || oldConfig == nil || oldConfig.Write.DSN != config.Write.DSN {
+		db, err := openDB(pool.driverName, config.Write, pool.options)
+		if err != nil {
+			return err
+		}
+		opened = append(opened, db)
+		if !pool.options.Lazy {
+			if err = pingWithRetry(db, pool.options, pool.closed); err != nil {
+				closeOpened()
+				return err
+			}
+		}
+		if writer != nil {
+			removed = append(removed, writer)
+		}
+		writer = db
+	} else {
+		resizeDB(writer, config.Write)
+	}
+
+	// readers are matched by DSN
+	used := make([]bool, len(oldReaders))
+	readers := make([]*sql.DB, len(config.Read))
+	status := make([]ReaderStatus, len(config.Read))
+	var unchecked []int
+	for i, readerConf := range config.Read {
+		for j, old := range oldReaders {
+			if !used[j] && oldConfig != nil && j < len(oldConfig.Read) && oldConfig.Read[j].DSN == readerConf.DSN {
+				used[j] = true
+				readers[i], status[i] = old, oldStatus[j]
+				resizeDB(old, readerConf)
+				break
+			}
+		}
+		if readers[i] != nil {
+			continue
+		}
+		db, err := openDB(pool.driverName, readerConf, pool.options)
+		if err != nil {
+			closeOpened()
+			return err
+		}
+		opened = append(opened, db)
+		readers[i] = db
+		unchecked = append(unchecked, i)
+	}
+	for j, old := range oldReaders {
+		if !used[j] {
+			removed = append(removed, old)
+		}
+	}
+	var unavailable []int
+	if !pool.options.Lazy {
+		for _, i := range unchecked {
+			if err := readers[i].Ping(); err != nil {
+				status[i] = ReaderStatus{Err: err, CheckedAt: time.Now()}
+				unavailable = append(unavailable, i)
+			}
+		}
+	}
+
+	pool.mu.Lock()
+	// the pool may be closed while reloading,the opened databases would never be closed
+	if pool.isClosed() {
+		pool.mu.Unlock()
+		closeOpened()
+		return ERR_POOLCLOSED
+	}
+	for _, db := range removed {
+		pool.unwatchReader(db)
+	}
+	for _, i := range unavailable {
+		go pool.reviveReader(readers[i], pool.options, pool.watchReader(readers[i]))
+	}
+	pool.dbConfig = config
+	pool.writer = writer
+	pool.reader = readers
+	pool.readerStatus = status
+	for _, db := range opened {
+		pool.addStmtCache(db)
+	}
+	pool.mu.Unlock()
+
+	for _, db := range removed {
+		go pool.drain(db)
+	}
+	return nil
+}
+
+// whether Close was called.
+func (pool *ConnPool) isClosed() bool {
+	select {
+	case <-pool.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// close the removed database after DrainDelay or the pool is closed.
+// The queries in flight are finished by sql.DB.Close.
+func (pool *ConnPool) drain(db *sql.DB) {
+	select {
+	case <-time.After(pool.options.DrainDelay):
+	case <-pool.closed:
+	}
+	pool.mu.Lock()
+	stmts := pool.stmtCaches[db]
+	delete(pool.stmtCaches, db)
+	pool.mu.Unlock()
+	if stmts != nil {
+		stmts.close()
+	}
+	_ = db.Close()
+}
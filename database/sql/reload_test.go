@@ -0,0 +1,129 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-30
+ */
+package sql
+
+import (
+	"database/sql"
+	"github.com/AbelZhou/even/database"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnPool_Reload(t *testing.T) {
+	dsn := func(port string) string { return "abel:123456@tcp(127.0.0.1:" + port + ")/test?timeout=100ms" }
+	pool, err := OpenMySQLPool(&database.Config{
+		Write: &database.DBConfig{DSN: dsn("3306")},
+		Read:  []*database.DBConfig{{DSN: dsn("3307")}, {DSN: dsn("3308")}},
+	}, PoolOptions{Lazy: true, DrainDelay: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	writer, reader0, reader1 := pool.writer, pool.reader[0], pool.reader[1]
+
+	err = pool.Reload(&database.Config{
+		Write:        &database.DBConfig{DSN: dsn("3306"), MaxActive: 50},
+		Read:         []*database.DBConfig{{DSN: dsn("3308")}, {DSN: dsn("3309")}},
+		DefMaxActive: 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.master() != writer || writer.Stats().MaxOpenConnections != 50 {
+		t.Error("The unchanged writer must be kept and resized.")
+	}
+	if len(pool.reader) != 2 || pool.reader[0] != reader1 || pool.reader[1] == reader0 {
+		t.Error("The readers must be matched by DSN.")
+	}
+	if reader1.Stats().MaxOpenConnections != 20 {
+		t.Error("The kept reader must be resized by default config.")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err = reader0.Ping(); err == nil || err.Error() != "sql: database is closed" {
+		t.Errorf("The removed reader must be drained.got:%v", err)
+	}
+	if err = reader1.Ping(); err != nil && err.Error() == "sql: database is closed" {
+		t.Error("The kept reader must not be closed.")
+	}
+}
+
+func TestConnPool_ReloadConcurrently(t *testing.T) {
+	pool, err := OpenPool(&database.Config{Write: &database.DBConfig{DSN: "0"}}, "even_test_slow",
+		PoolOptions{DrainDelay: 10 * time.Millisecond, StmtCacheSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(dsn string) {
+			defer wg.Done()
+			if err := pool.Reload(&database.Config{Write: &database.DBConfig{DSN: dsn}}); err != nil {
+				t.Error(err)
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	time.Sleep(100 * time.Millisecond)
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	// the databases opened by overlapping reloads are never drained without reloadMu
+	inUse := map[*sql.DB]bool{pool.writer: true}
+	for _, db := range pool.reader {
+		inUse[db] = true
+	}
+	for db := range pool.stmtCaches {
+		if !inUse[db] {
+			t.Fatal("The replaced databases must be drained.")
+		}
+	}
+}
+
+func TestConnPool_ReloadRevive(t *testing.T) {
+	pool, err := OpenPool(&database.Config{
+		Write: &database.DBConfig{DSN: "writer"},
+		Read:  []*database.DBConfig{{DSN: "down"}},
+	}, "even_test_script", PoolOptions{Backoff: time.Millisecond, MaxBackoff: time.Millisecond, DrainDelay: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if pool.ReaderStatus()[0].Err == nil {
+		t.Fatal("The reader must be unavailable.")
+	}
+
+	if err = pool.Reload(&database.Config{
+		Write: &database.DBConfig{DSN: "writer"},
+		Read:  []*database.DBConfig{{DSN: "reader"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// the removed reader is not closed until DrainDelay,reviving must be stopped by Reload
+	time.Sleep(20 * time.Millisecond)
+	opens := atomic.LoadInt32(&downOpens)
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&downOpens); n != opens {
+		t.Errorf("The removed reader must not be revived.got %d pings", n-opens)
+	}
+	pool.mu.RLock()
+	revives := len(pool.revives)
+	pool.mu.RUnlock()
+	if revives != 0 {
+		t.Errorf("The reviving of removed reader must be unwatched.got:%d", revives)
+	}
+
+	_ = pool.Close()
+	if err = pool.Reload(&database.Config{Write: &database.DBConfig{DSN: "writer"}}); err != ERR_POOLCLOSED {
+		t.Errorf("Reload after closed must return an error.got:%v", err)
+	}
+}
@@ -0,0 +1,161 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-17
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+)
+
+// Rows is a cursor which streams the result row by row with constant memory.
+// The conn can not be used until the Rows is closed.The result is never cached.
+type Rows struct {
+	conn    *Conn
+	rows    *sql.Rows
+	columns []*sql.ColumnType
+	cols    []string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	event   *Event
+	err     error
+	closed  bool
+}
+
+// query and return a cursor.
+// The Rows must be closed after use.
+func (conn *Conn) Rows() (*Rows, error) {
+	return conn.RowsContext(context.Background())
+}
+
+// query with context and return a cursor.
+func (conn *Conn) RowsContext(ctx context.Context) (*Rows, error) {
+	ctx, cancel := conn.callContext(ctx)
+	ctx, event, err := conn.intercept(ctx, OpQuery)
+	r := &Rows{conn: conn, ctx: ctx, cancel: cancel, event: event}
+	if err != nil {
+		r.err = err
+		_ = r.Close()
+		return nil, err
+	}
+
+	if r.rows, err = conn.query(ctx); err != nil {
+		r.err = err
+		_ = r.Close()
+		return nil, err
+	}
+	if r.columns, err = r.rows.ColumnTypes(); err != nil {
+		r.err = err
+		_ = r.Close()
+		return nil, err
+	}
+	r.cols = make([]string, len(r.columns))
+	for i, column := range r.columns {
+		r.cols[i] = column.Name()
+	}
+	return r, nil
+}
+
+// call fn for every row.
+// It stops and returns the error when fn returns an error.
+func (conn *Conn) Each(fn func(row *Rows) error) error {
+	return conn.EachContext(context.Background(), fn)
+}
+
+// call fn for every row with context.
+func (conn *Conn) EachContext(ctx context.Context, fn func(row *Rows) error) (err error) {
+	rows, err := conn.RowsContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		if err = fn(rows); err != nil {
+			rows.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare the next row.It returns false when there is no more row or an error occurs.
+func (r *Rows) Next() bool {
+	if r.closed {
+		return false
+	}
+	if r.rows.Next() {
+		r.event.RowCount++
+		return true
+	}
+	return false
+}
+
+// scan the current row into v,v must be a pointer to struct or primitive value.
+// The columns are mapped to the fields like ScanOne.
+func (r *Rows) Scan(v interface{}) error {
+	vType := reflect.TypeOf(v)
+	if vType == nil || vType.Kind() != reflect.Ptr {
+		return ERR_MUSTBEPOINTER
+	}
+	if vType.Elem().Kind() == reflect.Slice {
+		return ERR_MUSTNOTBESLICE
+	}
+	if err := scanValue(r.rows, r.cols, reflect.ValueOf(v).Elem(), r.conn.strict); err != nil {
+		return contextErr(r.ctx, err)
+	}
+	return nil
+}
+
+// get the current row as a map like FetchOne.
+func (r *Rows) Map() (map[string]interface{}, error) {
+	m, err := scanMap(r.rows, r.columns)
+	if err != nil {
+		return nil, contextErr(r.ctx, err)
+	}
+	return m, nil
+}
+
+// get the column names.
+func (r *Rows) Columns() []string {
+	return r.cols
+}
+
+// get the error during iteration.
+func (r *Rows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.rows == nil {
+		return nil
+	}
+	return contextErr(r.ctx, r.rows.Err())
+}
+
+// close the cursor and release the conn.
+// It's safe to call Close more than once.
+func (r *Rows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	var err error
+	if r.rows != nil {
+		err = r.rows.Close()
+	}
+	if err == nil {
+		err = r.Err()
+	}
+	r.conn.complete(r.ctx, r.event, err)
+	r.cancel()
+	r.conn.clear()
+	return err
+}
@@ -0,0 +1,155 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-17
+ */
+package sql
+
+import (
+	"context"
+	"errors"
+	"github.com/AbelZhou/even/database"
+	"testing"
+	"time"
+)
+
+// records the completed events.
+type eventRecorder struct {
+	events []Event
+}
+
+func (r *eventRecorder) Before(ctx context.Context, event *Event) (context.Context, error) {
+	return ctx, nil
+}
+
+func (r *eventRecorder) After(ctx context.Context, event *Event) {
+	r.events = append(r.events, *event)
+}
+
+// the writer has only one connection,so the leaked connection blocks the next query.
+func openSlowPool(t *testing.T) (*ConnPool, *eventRecorder) {
+	t.Helper()
+	pool, err := OpenPool(&database.Config{Write: &database.DBConfig{DSN: "slow", MaxActive: 1}}, "even_test_slow",
+		PoolOptions{Lazy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &eventRecorder{}
+	pool.Use(recorder)
+	return pool, recorder
+}
+
+func TestRows(t *testing.T) {
+	pool, recorder := openSlowPool(t)
+	defer pool.Close()
+	defer func() { onRow = nil }()
+	produced := -1
+	onRow = func(n int) error {
+		produced = n
+		return nil
+	}
+
+	rows, err := pool.Master().Prepared("SELECT id FROM slow").Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if !rows.Next() {
+			t.Fatal(rows.Err())
+		}
+		var id int64
+		if err = rows.Scan(&id); err != nil || id != int64(i) {
+			t.Fatalf("Scan mismatch.got:%d %v", id, err)
+		}
+		if produced != i {
+			t.Fatalf("The rows must be streamed without buffering.read:%d produced:%d", i, produced)
+		}
+	}
+	if len(recorder.events) != 0 {
+		t.Fatal("The query must be completed by Close.")
+	}
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].RowCount != 3 || recorder.events[0].Err != nil {
+		t.Fatalf("The completion hook must be run once by Close.got:%+v", recorder.events)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err = pool.Master().Prepared("SELECT id FROM slow").FetchOneContext(ctx); err != nil {
+		t.Fatalf("The connection must be released by Close.got:%v", err)
+	}
+}
+
+func TestEach(t *testing.T) {
+	pool, recorder := openSlowPool(t)
+	defer pool.Close()
+
+	stop := errors.New("stop")
+	calls := 0
+	err := pool.Master().Prepared("SELECT id FROM slow").Each(func(row *Rows) error {
+		if calls++; calls == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 3 {
+		t.Fatalf("Each must stop and return the error of fn.got:%d calls,%v", calls, err)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Err != stop {
+		t.Fatalf("The completion hook must get the error of fn.got:%+v", recorder.events)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err = pool.Master().Prepared("SELECT id FROM slow").FetchOneContext(ctx); err != nil {
+		t.Fatalf("The connection must be released by Each.got:%v", err)
+	}
+}
+
+func TestEach_Canceled(t *testing.T) {
+	pool, _ := openSlowPool(t)
+	defer pool.Close()
+	defer func() { onRow = nil }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	onRow = func(n int) error {
+		if n == 2 {
+			cancel()
+		}
+		return nil
+	}
+	calls := 0
+	err := pool.Master().Prepared("SELECT id FROM slow").EachContext(ctx, func(row *Rows) error {
+		calls++
+		return nil
+	})
+	if err != ERR_CANCELED || calls > 3 {
+		t.Fatalf("The canceled iteration must return ERR_CANCELED.got:%d calls,%v", calls, err)
+	}
+
+	rowsCtx, rowsCancel := context.WithCancel(context.Background())
+	defer rowsCancel()
+	onRow = func(n int) error {
+		if n == 2 {
+			rowsCancel()
+		}
+		return nil
+	}
+	rows, err := pool.Master().Prepared("SELECT id FROM slow").RowsContext(rowsCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err = rows.Err(); err != ERR_CANCELED {
+		t.Errorf("Err must return ERR_CANCELED.got:%v", err)
+	}
+	if err = rows.Close(); err != ERR_CANCELED {
+		t.Errorf("Close must return ERR_CANCELED.got:%v", err)
+	}
+}
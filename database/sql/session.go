@@ -0,0 +1,148 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-05
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Consistency is the read-your-writes config of the pool.
+// The reads after the window are not affected by the write in both modes.
+type Consistency struct {
+	Window      time.Duration //the reads go to the writer in the window after the latest write
+	WaitGTID    bool          //wait for the reader to catch up the GTID of the latest write in the window instead
+	WaitTimeout time.Duration //max wait for GTID,the writer is used after timeout
+}
+
+// Session remembers the latest write of a user or request.
+// The reads by the session can see its own writes.It's safe for concurrent use.
+type Session struct {
+	mu        sync.Mutex
+	lastWrite time.Time
+	gtid      string
+}
+
+func NewSession() *Session {
+	return &Session{}
+}
+
+func (s *Session) record(gtid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastWrite = time.Now()
+	if gtid != "" {
+		s.gtid = gtid
+	}
+}
+
+func (s *Session) state() (time.Time, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastWrite, s.gtid
+}
+
+type sessionKey struct{}
+
+// bind the session to the context of request.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// get the session bound to the context.
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// set the read-your-writes config.
+// It should be called before the pool is used.
+func (pool *ConnPool) SetConsistency(consistency Consistency) {
+	pool.consistency = consistency
+}
+
+// get a reader connection which can see the writes of the session.
+// It's the writer within the window after the latest write,
+// or the reader has caught up the GTID of the write when WaitGTID is set.
+func (pool *ConnPool) SlaveSession(s *Session) *Conn {
+	if s == nil {
+		return pool.Slave()
+	}
+	lastWrite, gtid := s.state()
+	if lastWrite.IsZero() || time.Since(lastWrite) >= pool.consistency.Window {
+		return pool.Slave()
+	}
+
+	if pool.consistency.WaitGTID && gtid != "" {
+		db, writer := pool.pickReader(""), pool.master()
+		if db != writer && waitGTID(db, gtid, pool.consistency.WaitTimeout) != nil {
+			db = writer
+		}
+		return pool.newConn(db, true)
+	}
+	return pool.newConn(pool.master(), true)
+}
+
+// get a reader connection by the session bound to the context.
+func (pool *ConnPool) SlaveContext(ctx context.Context) *Conn {
+	return pool.SlaveSession(SessionFromContext(ctx))
+}
+
+// record the writes of the connection into the session.
+func (conn *Conn) Session(s *Session) *Conn {
+	conn.session = s
+	return conn
+}
+
+// record the write into the session of the conn or context.
+// The write in transaction is recorded after commit.
+func (conn *Conn) recordWrite(ctx context.Context) {
+	s := conn.session
+	if s == nil {
+		s = SessionFromContext(ctx)
+	}
+	if s == nil {
+		return
+	}
+	if conn.inTransaction {
+		conn.txSession = s
+		return
+	}
+	conn.recordSession(ctx, s)
+}
+
+// record the write time and the GTID of writer into session.
+// It costs one more round-trip after every write or commit when WaitGTID is set,
+// because the driver does not expose the GTID tracked in the OK packet.
+func (conn *Conn) recordSession(ctx context.Context, s *Session) {
+	gtid := ""
+	if conn.consistency.WaitGTID {
+		// the global set contains the write,it's empty when gtid_mode is off.
+		_ = conn.db.QueryRowContext(ctx, "SELECT @@GLOBAL.gtid_executed").Scan(&gtid)
+	}
+	s.record(gtid)
+}
+
+// wait for the reader to execute the GTID set.
+func waitGTID(db *sql.DB, gtid string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+time.Second)
+	defer cancel()
+
+	var res int
+	err := db.QueryRowContext(ctx, "SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)", gtid, timeout.Seconds()).Scan(&res)
+	if err != nil {
+		return contextErr(ctx, err)
+	}
+	if res != 0 {
+		return ERR_TIMEOUT
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-05
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/AbelZhou/even/database"
+	"testing"
+	"time"
+)
+
+func TestConnPool_SlaveSession(t *testing.T) {
+	writer, _ := sql.Open("even_mysql", "abel:123456@tcp(127.0.0.1:3306)/test")
+	reader, _ := sql.Open("even_mysql", "abel:123456@tcp(127.0.0.1:3307)/test")
+	pool := &ConnPool{
+		dbConfig:     &database.Config{Read: []*database.DBConfig{{}}},
+		writer:       writer,
+		reader:       []*sql.DB{reader},
+		readerStatus: make([]ReaderStatus, 1),
+	}
+	pool.SetConsistency(Consistency{Window: time.Minute})
+
+	session := NewSession()
+	ctx := WithSession(context.Background(), session)
+	if pool.SlaveContext(ctx).db != reader {
+		t.Fatal("The session without writes must read from the reader.")
+	}
+
+	// the write in transaction is recorded after commit.
+	conn := pool.Master()
+	conn.inTransaction = true
+	conn.recordWrite(ctx)
+	if pool.SlaveSession(session).db != reader {
+		t.Fatal("The uncommitted write must not be recorded.")
+	}
+	if err := conn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	slave := pool.SlaveSession(session)
+	if slave.db != writer || !slave.isReader {
+		t.Fatal("The session must read from the writer in the window after write.")
+	}
+
+	session.lastWrite = time.Now().Add(-2 * time.Minute)
+	if pool.SlaveSession(session).db != reader {
+		t.Fatal("The session must read from the reader after the window.")
+	}
+}
+
+func TestConnPool_SlaveSession_WaitGTID(t *testing.T) {
+	writer, _ := sql.Open("even_test_script", "writer")
+	reader, _ := sql.Open("even_test_script", "reader")
+	pool := &ConnPool{
+		dbConfig:     &database.Config{Read: []*database.DBConfig{{}}},
+		writer:       writer,
+		reader:       []*sql.DB{reader},
+		readerStatus: make([]ReaderStatus, 1),
+	}
+	pool.SetConsistency(Consistency{Window: time.Minute, WaitGTID: true, WaitTimeout: 100 * time.Millisecond})
+	defer resetScript(nil)
+	caughtUp := int64(0)
+	resetScript(func(query string, args []driver.Value) (*scriptResult, error) {
+		return &scriptResult{columns: []string{"res"}, rows: [][]driver.Value{{caughtUp}}}, nil
+	})
+
+	session := NewSession()
+	session.record("3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5")
+	if pool.SlaveSession(session).db != reader {
+		t.Error("The session must read from the reader which has caught up.")
+	}
+	caughtUp = 1
+	if pool.SlaveSession(session).db != writer {
+		t.Error("The session must read from the writer when the reader is behind.")
+	}
+
+	resetScript(nil)
+	session.lastWrite = time.Now().Add(-2 * time.Minute)
+	if pool.SlaveSession(session).db != reader {
+		t.Error("The session must read from the reader after the window.")
+	}
+	if statements := recordedStatements(); len(statements) != 0 {
+		t.Errorf("The GTID must not be waited after the window.got:%v", statements)
+	}
+}
@@ -0,0 +1,245 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-26
+ */
+package sql
+
+import (
+	"context"
+	"fmt"
+	"github.com/AbelZhou/even/database"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ShardOptions is the sharding rule of ShardedPool.
+type ShardOptions struct {
+	Tables        int      //physical tables of each logical table.Default the count of clusters
+	Sharding      Sharding //Default NewModSharding()
+	LogicalTables []string //the tables rewritten to physical tables,eg: user -> user_07
+	Concurrency   int      //max concurrent queries of scatter-gather.Default 8
+}
+
+// ShardedPool routes the connections to the clusters by shard key.
+// The physical tables are distributed to the clusters in order,
+// eg: 16 tables in 4 clusters,user_00~user_03 are in cluster 0.
+type ShardedPool struct {
+	pools   []*ConnPool
+	options ShardOptions
+	width   int //width of the physical table index
+}
+
+func NewShardedPool(pools []*ConnPool, options ShardOptions) (*ShardedPool, error) {
+	if len(pools) == 0 {
+		return nil, ERR_NOSHARDS
+	}
+	if options.Tables <= 0 {
+		options.Tables = len(pools)
+	}
+	if options.Tables < len(pools) {
+		return nil, fmt.Errorf("the tables %d are less than the clusters %d", options.Tables, len(pools))
+	}
+	// the same SQL would be sent to a cluster once per table without rewriting
+	if options.Tables > len(pools) && len(options.LogicalTables) == 0 {
+		return nil, fmt.Errorf("the logical tables are required when the tables %d are more than the clusters %d", options.Tables, len(pools))
+	}
+	if options.Sharding == nil {
+		options.Sharding = NewModSharding()
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 8
+	}
+	width := len(strconv.Itoa(options.Tables - 1))
+	if width < 2 {
+		width = 2
+	}
+	return &ShardedPool{pools: pools, options: options, width: width}, nil
+}
+
+// open the pools of clusters.The opened pools are closed when one of them failed.
+func OpenShardedPool(configs []*database.Config, driverName string, poolOptions PoolOptions, options ShardOptions) (*ShardedPool, error) {
+	pools := make([]*ConnPool, 0, len(configs))
+	closeAll := func() {
+		for _, pool := range pools {
+			_ = pool.Close()
+		}
+	}
+	for _, config := range configs {
+		pool, err := OpenPool(config, driverName, poolOptions)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+	shard, err := NewShardedPool(pools, options)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return shard, nil
+}
+
+// the pools of clusters.
+func (shard *ShardedPool) Pools() []*ConnPool {
+	return shard.pools
+}
+
+// close the pools of all clusters.
+func (shard *ShardedPool) Close() error {
+	var err error
+	for _, pool := range shard.pools {
+		if pErr := pool.Close(); pErr != nil && err == nil {
+			err = pErr
+		}
+	}
+	return err
+}
+
+// get the index of cluster and physical table of key.
+func (shard *ShardedPool) Shard(key interface{}) (cluster int, table int, err error) {
+	table, err = shard.options.Sharding.Shard(key, shard.options.Tables)
+	if err != nil {
+		return 0, 0, err
+	}
+	return shard.cluster(table), table, nil
+}
+
+func (shard *ShardedPool) cluster(table int) int {
+	return table * len(shard.pools) / shard.options.Tables
+}
+
+// get the physical table name,eg: user_07.
+func (shard *ShardedPool) Table(logical string, table int) string {
+	return fmt.Sprintf("%s_%0*d", logical, shard.width, table)
+}
+
+// get a writer connection of the shard of key.
+// The logical tables in SQL are rewritten to the physical tables.
+func (shard *ShardedPool) Master(key interface{}) (*Conn, error) {
+	cluster, table, err := shard.Shard(key)
+	if err != nil {
+		return nil, err
+	}
+	return shard.bind(shard.pools[cluster].Master(), table), nil
+}
+
+// get a reader connection of the shard of key.
+func (shard *ShardedPool) Slave(key interface{}) (*Conn, error) {
+	cluster, table, err := shard.Shard(key)
+	if err != nil {
+		return nil, err
+	}
+	return shard.bind(shard.pools[cluster].Slave(), table), nil
+}
+
+// bind the physical tables to conn.
+func (shard *ShardedPool) bind(conn *Conn, table int) *Conn {
+	conn.shardTables = make(map[string]string, len(shard.options.LogicalTables))
+	for _, logical := range shard.options.LogicalTables {
+		conn.shardTables[strings.ToLower(logical)] = shard.Table(logical, table)
+	}
+	return conn
+}
+
+// query all the physical tables by readers and merge the results in order of tables.
+// The sql must query the logical tables,otherwise a cluster returns the same rows once per table.
+// The ORDER BY and LIMIT are applied in every table,the merged result should be sorted by caller.
+func (shard *ShardedPool) FetchAll(sql string, args ...interface{}) ([]map[string]interface{}, error) {
+	return shard.FetchAllContext(context.Background(), sql, args...)
+}
+
+// query all the physical tables with context.The others are canceled when one failed.
+func (shard *ShardedPool) FetchAllContext(ctx context.Context, sql string, args ...interface{}) ([]map[string]interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	results := make([][]map[string]interface{}, shard.options.Tables)
+	sem := make(chan struct{}, shard.options.Concurrency)
+	for table := 0; table < shard.options.Tables; table++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(table int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			conn := shard.bind(shard.pools[shard.cluster(table)].Slave(), table)
+			rows, err := conn.Prepared(sql, args...).FetchAllContext(ctx)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("query table %d failed: %v", table, err)
+					cancel()
+				})
+				return
+			}
+			results[table] = rows
+		}(table)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var merged []map[string]interface{}
+	for _, rows := range results {
+		merged = append(merged, rows...)
+	}
+	return merged, nil
+}
+
+// rewrite the logical tables to physical tables.
+// The identifiers in SQL equal to the logical tables are replaced,including the qualifier of columns,
+// so the columns must not be named as the logical tables.
+func rewriteTables(sql string, tables map[string]string) string {
+	if len(tables) == 0 {
+		return sql
+	}
+	var buf strings.Builder
+	runes := []rune(sql)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"':
+			start := i
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			end := i + 1
+			if end > len(runes) {
+				end = len(runes)
+			}
+			buf.WriteString(string(runes[start:end]))
+		case r == '`':
+			start := i + 1
+			for i++; i < len(runes) && runes[i] != '`'; i++ {
+			}
+			name := string(runes[start:i])
+			if physical, ok := tables[strings.ToLower(name)]; ok {
+				name = physical
+			}
+			buf.WriteString("`" + name + "`")
+		case isIdentRune(r) && (i == 0 || !isIdentRune(runes[i-1])):
+			start := i
+			for ; i < len(runes) && isIdentRune(runes[i]); i++ {
+			}
+			name := string(runes[start:i])
+			if physical, ok := tables[strings.ToLower(name)]; ok && !unicode.IsDigit(runes[start]) {
+				name = physical
+			}
+			buf.WriteString(name)
+			i--
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
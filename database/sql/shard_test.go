@@ -0,0 +1,84 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-26
+ */
+package sql
+
+import "testing"
+
+func TestSharding(t *testing.T) {
+	if table, err := NewModSharding().Shard(int64(23), 16); err != nil || table != 7 {
+		t.Errorf("Mod sharding mismatch.got:%d %v", table, err)
+	}
+	if _, err := NewModSharding().Shard(1.5, 16); err != ERR_SHARDKEY {
+		t.Errorf("The float key must be unsupported.got:%v", err)
+	}
+
+	ranges := NewRangeSharding(100, 200)
+	if table, err := ranges.Shard(100, 2); err != nil || table != 1 {
+		t.Errorf("Range sharding mismatch.got:%d %v", table, err)
+	}
+	if _, err := ranges.Shard(200, 2); err != ERR_SHARDNOTFOUND {
+		t.Errorf("The key out of ranges must not be found.got:%v", err)
+	}
+
+	hash := NewConsistentHashSharding(0)
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		before, _ := hash.Shard(i, 8)
+		after, _ := hash.Shard(i, 9)
+		if again, _ := hash.Shard(i, 8); again != before {
+			t.Fatal("Consistent hash sharding must be stable.")
+		}
+		if before != after {
+			moved++
+		}
+	}
+	if moved > 250 {
+		t.Errorf("Too many keys are moved by adding a table.moved:%d", moved)
+	}
+}
+
+func TestBeforePrepared_ShardTables(t *testing.T) {
+	conn := &Conn{shardTables: map[string]string{"user": "user_07"}}
+	query, args, err := conn.beforePrepared("SELECT * FROM `user` WHERE id = :user AND nickname = ':user'",
+		map[string]interface{}{"user": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "SELECT * FROM `user_07` WHERE id = ? AND nickname = ':user'" || len(args) != 1 || args[0] != 1 {
+		t.Errorf("The named arg must not be rewritten as a table.got:%s %v", query, args)
+	}
+}
+
+func TestShardedPool(t *testing.T) {
+	shard, err := NewShardedPool([]*ConnPool{{}, {}, {}, {}}, ShardOptions{Tables: 16, LogicalTables: []string{"user"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster, table, err := shard.Shard(23)
+	if err != nil || cluster != 1 || table != 7 {
+		t.Errorf("Shard mismatch.got:%d %d %v", cluster, table, err)
+	}
+
+	conn, err := shard.Master(23)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Prepared("SELECT user.id, 'user' FROM `user` JOIN user_profile ON user.id = user_profile.user_id WHERE id = ?", 23)
+	expect := "SELECT user_07.id, 'user' FROM `user_07` JOIN user_profile ON user_07.id = user_profile.user_id WHERE id = ?"
+	if conn.preparedSql != expect {
+		t.Errorf("Rewrite tables mismatch.got:%s", conn.preparedSql)
+	}
+
+	if _, err = NewShardedPool([]*ConnPool{{}, {}}, ShardOptions{Tables: 1}); err == nil {
+		t.Error("The tables less than clusters must return an error.")
+	}
+	if _, err = NewShardedPool([]*ConnPool{{}, {}}, ShardOptions{Tables: 4}); err == nil {
+		t.Error("The tables more than clusters without logical tables must return an error.")
+	}
+	if _, err = NewShardedPool([]*ConnPool{{}, {}}, ShardOptions{}); err != nil {
+		t.Errorf("The table per cluster needs no logical tables.got:%v", err)
+	}
+}
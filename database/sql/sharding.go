@@ -0,0 +1,147 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-26
+ */
+package sql
+
+import (
+	"hash/crc32"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Sharding maps the shard key to the index of physical table in [0,tables).
+type Sharding interface {
+	Shard(key interface{}, tables int) (int, error)
+}
+
+// the integer key is used directly,the string key is hashed by crc32.
+func shardHash(key interface{}) (uint64, bool, error) {
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		if i < 0 {
+			i = -i
+		}
+		return uint64(i), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), true, nil
+	case reflect.String:
+		return uint64(crc32.ChecksumIEEE([]byte(v.String()))), false, nil
+	case reflect.Slice:
+		if b, ok := key.([]byte); ok {
+			return uint64(crc32.ChecksumIEEE(b)), false, nil
+		}
+	}
+	return 0, false, ERR_SHARDKEY
+}
+
+type modSharding struct{}
+
+// shard by key % tables.
+func NewModSharding() Sharding {
+	return modSharding{}
+}
+
+func (modSharding) Shard(key interface{}, tables int) (int, error) {
+	h, _, err := shardHash(key)
+	if err != nil {
+		return 0, err
+	}
+	return int(h % uint64(tables)), nil
+}
+
+type rangeSharding struct {
+	bounds []int64
+}
+
+// shard the integer key by ranges,table i holds the keys in [bounds[i-1],bounds[i]).
+// eg: NewRangeSharding(10000000, 20000000) puts id 10000000 to table 1.
+func NewRangeSharding(bounds ...int64) Sharding {
+	return rangeSharding{bounds: bounds}
+}
+
+func (s rangeSharding) Shard(key interface{}, tables int) (int, error) {
+	h, isInt, err := shardHash(key)
+	if err != nil {
+		return 0, err
+	}
+	if !isInt {
+		return 0, ERR_SHARDKEY
+	}
+	if v := reflect.ValueOf(key); v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64 && v.Int() < 0 {
+		return 0, ERR_SHARDNOTFOUND
+	}
+	i := sort.Search(len(s.bounds), func(i int) bool { return h < uint64(s.bounds[i]) })
+	if i >= len(s.bounds) || i >= tables {
+		return 0, ERR_SHARDNOTFOUND
+	}
+	return i, nil
+}
+
+type consistentHashSharding struct {
+	vnodes int
+	rings  sync.Map //tables -> *hashRing
+}
+
+type hashRing struct {
+	hashes []uint32
+	tables []int
+}
+
+// shard by consistent hash ring with vnodes virtual nodes per table.Default 160.
+// Only a few keys are moved when the tables are increased.
+func NewConsistentHashSharding(vnodes int) Sharding {
+	if vnodes <= 0 {
+		vnodes = 160
+	}
+	return &consistentHashSharding{vnodes: vnodes}
+}
+
+func (s *consistentHashSharding) Shard(key interface{}, tables int) (int, error) {
+	var h uint32
+	if v := reflect.ValueOf(key); v.Kind() == reflect.String {
+		h = crc32.ChecksumIEEE([]byte(v.String()))
+	} else {
+		n, _, err := shardHash(key)
+		if err != nil {
+			return 0, err
+		}
+		h = crc32.ChecksumIEEE([]byte(strconv.FormatUint(n, 10)))
+	}
+	ring := s.ring(tables)
+	i := sort.Search(len(ring.hashes), func(i int) bool { return ring.hashes[i] >= h })
+	if i == len(ring.hashes) {
+		i = 0
+	}
+	return ring.tables[i], nil
+}
+
+// get the cached ring of tables.
+func (s *consistentHashSharding) ring(tables int) *hashRing {
+	if ring, ok := s.rings.Load(tables); ok {
+		return ring.(*hashRing)
+	}
+	type node struct {
+		hash  uint32
+		table int
+	}
+	nodes := make([]node, 0, tables*s.vnodes)
+	for t := 0; t < tables; t++ {
+		for v := 0; v < s.vnodes; v++ {
+			nodes = append(nodes, node{crc32.ChecksumIEEE([]byte(strconv.Itoa(t) + "#" + strconv.Itoa(v))), t})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].hash < nodes[j].hash })
+	ring := &hashRing{hashes: make([]uint32, len(nodes)), tables: make([]int, len(nodes))}
+	for i, n := range nodes {
+		ring.hashes[i] = n.hash
+		ring.tables[i] = n.table
+	}
+	actual, _ := s.rings.LoadOrStore(tables, ring)
+	return actual.(*hashRing)
+}
@@ -0,0 +1,253 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-06-25
+ */
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// format of slow log
+const (
+	SlowLogText = "text"
+	SlowLogJSON = "json"
+)
+
+// a record of json slow log.
+type slowRecord struct {
+	Time    string   `json:"time"`
+	Op      string   `json:"op"`
+	Elapsed float64  `json:"elapsed"` //Second
+	Target  string   `json:"target"`
+	Caller  string   `json:"caller"`
+	SQL     string   `json:"sql"`
+	Args    []string `json:"args"`
+	Rows    int64    `json:"rows"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// the directory of this package,used to find the caller out of it.
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// SlowLogger records the operations which exceed the threshold.
+// It's an Interceptor,register it by ConnPool.SetSlowLog or ConnPool.Use.
+type SlowLogger struct {
+	threshold time.Duration
+	format    string
+	mu        sync.Mutex
+	writer    io.Writer
+	closer    io.Closer
+}
+
+// create a slow logger writes to w.
+// format is SlowLogText or SlowLogJSON(one json object per line).
+func NewSlowLogger(w io.Writer, threshold time.Duration, format string) *SlowLogger {
+	return &SlowLogger{threshold: threshold, format: format, writer: w}
+}
+
+// create a slow logger appends to the file.
+func NewSlowFileLogger(path string, threshold time.Duration, format string) (*SlowLogger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	logger := NewSlowLogger(file, threshold, format)
+	logger.closer = file
+	return logger, nil
+}
+
+// close the log file.
+func (l *SlowLogger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+func (l *SlowLogger) Before(ctx context.Context, event *Event) (context.Context, error) {
+	return ctx, nil
+}
+
+func (l *SlowLogger) After(ctx context.Context, event *Event) {
+	if event.Cached || event.Duration < l.threshold {
+		return
+	}
+
+	target := "master"
+	if event.IsReader {
+		target = "slave"
+	}
+	errMsg := ""
+	if event.Err != nil {
+		errMsg = event.Err.Error()
+	}
+
+	var line []byte
+	if l.format == SlowLogJSON {
+		line, _ = json.Marshal(&slowRecord{
+			Time:    event.Start.Format("2006-01-02 15:04:05.000"),
+			Op:      event.Op,
+			Elapsed: event.Duration.Seconds(),
+			Target:  target,
+			Caller:  caller(),
+			SQL:     Interpolate(event.SQL, event.Args),
+			Args:    formatArgs(event.Args),
+			Rows:    event.RowCount,
+			Error:   errMsg,
+		})
+		line = append(line, '\n')
+	} else {
+		line = []byte(fmt.Sprintf("%s [SLOW] %s %.6fs %s %s rows:%d err:%s\n%s;\n",
+			event.Start.Format("2006-01-02 15:04:05.000"), event.Op, event.Duration.Seconds(),
+			target, caller(), event.RowCount, errMsg, Interpolate(event.SQL, event.Args)))
+	}
+
+	l.mu.Lock()
+	_, _ = l.writer.Write(line)
+	l.mu.Unlock()
+}
+
+// find the first caller out of this package.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// render args to strings for logging.
+func formatArgs(args []interface{}) []string {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		res[i] = quoteArg(arg)
+	}
+	return res
+}
+
+// replace the placeholders by the quoted args.
+// It gives the sql which can be executed by DBA directly,it's not used to execute queries.
+func Interpolate(sql string, args []interface{}) string {
+	if len(args) == 0 {
+		return sql
+	}
+	var buf strings.Builder
+	argIdx := 0
+	var (
+		quote   rune
+		escaped bool
+	)
+	for _, r := range sql {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			// the backslash escapes the next character in string literals
+			if r == '\\' && quote != '`' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?' && argIdx < len(args):
+			buf.WriteString(quoteArg(args[argIdx]))
+			argIdx++
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// quote arg as a mysql literal.
+func quoteArg(arg interface{}) string {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "NULL"
+		}
+		arg = v
+	}
+
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		if v.IsZero() {
+			return "'0000-00-00'"
+		}
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+	case []byte:
+		if v == nil {
+			return "NULL"
+		}
+		return quoteString(string(v))
+	case string:
+		return quoteString(v)
+	default:
+		return quoteString(fmt.Sprint(v))
+	}
+}
+
+// quote and escape string like mysql_real_escape_string.
+func quoteString(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			buf.WriteString(`\0`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\x1a':
+			buf.WriteString(`\Z`)
+		case '\'':
+			buf.WriteString(`\'`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('\'')
+	return buf.String()
+}
@@ -0,0 +1,48 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-06-25
+ */
+package sql
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	now := time.Date(2019, 6, 25, 10, 30, 0, 0, time.Local)
+	sql := Interpolate("SELECT * FROM `usertest` WHERE nickname=? AND mobile<>'?' AND create_time<? AND id IN (?,?) AND deleted=?",
+		[]interface{}{"it's\n", now, 1, int64(2), nil})
+	expect := "SELECT * FROM `usertest` WHERE nickname='it\\'s\\n' AND mobile<>'?' AND create_time<'2019-06-25 10:30:00' AND id IN (1,2) AND deleted=NULL"
+	if sql != expect {
+		t.Errorf("Interpolate failed.\nexpect:%s\ngot:   %s", expect, sql)
+	}
+
+	sql = Interpolate(`SELECT * FROM usertest WHERE nickname='it\'s ?' AND id=?`, []interface{}{1})
+	if expect = `SELECT * FROM usertest WHERE nickname='it\'s ?' AND id=1`; sql != expect {
+		t.Errorf("The escaped quote must not end the string.\nexpect:%s\ngot:   %s", expect, sql)
+	}
+}
+
+func TestSlowLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlowLogger(&buf, 100*time.Millisecond, SlowLogJSON)
+
+	logger.After(context.Background(), &Event{Op: OpQuery, SQL: "SELECT 1", Duration: time.Millisecond})
+	if buf.Len() != 0 {
+		t.Fatalf("The fast query must not be logged.%s", buf.String())
+	}
+
+	logger.After(context.Background(), &Event{Op: OpQuery, SQL: "SELECT * FROM `usertest` WHERE id=?", Args: []interface{}{1},
+		IsReader: true, Duration: time.Second})
+	line := buf.String()
+	for _, s := range []string{`"sql":"SELECT * FROM ` + "`usertest`" + ` WHERE id=1"`, `"target":"slave"`, `slowlog_test.go:`} {
+		if !strings.Contains(line, s) {
+			t.Errorf("Slow log must contain %s.got:%s", s, line)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-15
+ */
+package sql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// stmtCache is a LRU cache of prepared statements on a *sql.DB keyed by sql.
+// The statement is prepared on every connection of the DB lazily by database/sql.
+type stmtCache struct {
+	mu    sync.Mutex
+	db    *sql.DB
+	size  int
+	ll    *list.List //front is the most recently used
+	items map[string]*list.Element
+}
+
+type stmtEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(db *sql.DB, size int) *stmtCache {
+	return &stmtCache{db: db, size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get the statement of query and a function to release it after use.
+// The evicted statement is closed after all the users release it.
+func (c *stmtCache) get(ctx context.Context, query string) (*sql.Stmt, func(), error) {
+	if stmt, release, ok := c.lookup(query); ok {
+		return stmt, release, nil
+	}
+
+	// prepare without lock,the slow prepare must not block others.
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[query]; ok {
+		// prepared by others at the same time.
+		_ = stmt.Close()
+		entry := c.use(el)
+		return entry.stmt, c.releaser(entry), nil
+	}
+
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	c.items[query] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.size {
+		c.evict(c.ll.Back())
+	}
+	return stmt, c.releaser(entry), nil
+}
+
+// get the cached statement of query,it's not prepared when missing.
+func (c *stmtCache) lookup(query string) (*sql.Stmt, func(), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[query]
+	if !ok {
+		return nil, nil, false
+	}
+	entry := c.use(el)
+	return entry.stmt, c.releaser(entry), true
+}
+
+func (c *stmtCache) use(el *list.Element) *stmtEntry {
+	c.ll.MoveToFront(el)
+	entry := el.Value.(*stmtEntry)
+	entry.refs++
+	return entry
+}
+
+func (c *stmtCache) releaser(entry *stmtEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			entry.refs--
+			if entry.evicted && entry.refs == 0 {
+				_ = entry.stmt.Close()
+			}
+		})
+	}
+}
+
+func (c *stmtCache) evict(el *list.Element) {
+	entry := c.ll.Remove(el).(*stmtEntry)
+	delete(c.items, entry.query)
+	entry.evicted = true
+	if entry.refs == 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+// close all the statements.
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.ll.Len() > 0 {
+		c.evict(c.ll.Back())
+	}
+}
@@ -0,0 +1,166 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-15
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/AbelZhou/even/database"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// the closed statement can not be executed.
+func stmtClosed(stmt *sql.Stmt) bool {
+	_, err := stmt.Exec()
+	return err != nil && err.Error() == "sql: statement is closed"
+}
+
+func TestStmtCache(t *testing.T) {
+	db, _ := sql.Open("even_test_script", "stmt")
+	defer db.Close()
+	ctx := context.Background()
+	c := newStmtCache(db, 2)
+	get := func(query string) (*sql.Stmt, func()) {
+		t.Helper()
+		stmt, release, err := c.get(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stmt, release
+	}
+
+	// size bound and LRU
+	a, release := get("a")
+	release()
+	b, release := get("b")
+	release()
+	if again, release := get("a"); again != a {
+		t.Fatal("The cached statement must be reused.")
+	} else {
+		release()
+	}
+	_, release = get("c")
+	release()
+	if c.ll.Len() != 2 || len(c.items) != 2 || c.items["b"] != nil {
+		t.Fatalf("The least recently used statement must be evicted.got:%d %v", c.ll.Len(), c.items)
+	}
+	if !stmtClosed(b) || stmtClosed(a) {
+		t.Fatal("Only the evicted statement must be closed.")
+	}
+
+	// evicted while it's held
+	held, releaseHeld := get("d")
+	_, release = get("e")
+	release()
+	_, release = get("f")
+	release()
+	if c.items["d"] != nil {
+		t.Fatal("The held statement must be evicted from the cache.")
+	}
+	if stmtClosed(held) {
+		t.Fatal("The held statement must not be closed before it's released.")
+	}
+	releaseHeld()
+	releaseHeld()
+	if !stmtClosed(held) {
+		t.Fatal("The evicted statement must be closed after it's released.")
+	}
+	if again, release := get("d"); again == held {
+		t.Fatal("The evicted statement must be prepared again.")
+	} else {
+		release()
+	}
+
+	// close with the statements in use
+	held, releaseHeld = get("f")
+	c.close()
+	if c.ll.Len() != 0 || len(c.items) != 0 {
+		t.Fatal("All the statements must be removed by close.")
+	}
+	if stmtClosed(held) {
+		t.Fatal("The statement in use must not be closed by close.")
+	}
+	releaseHeld()
+	if !stmtClosed(held) {
+		t.Fatal("The statement must be closed after it's released.")
+	}
+}
+
+// run with -race
+func TestStmtCache_Concurrent(t *testing.T) {
+	db, _ := sql.Open("even_test_script", "stmt")
+	defer db.Close()
+	c := newStmtCache(db, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				stmt, release, err := c.get(context.Background(), strconv.Itoa((i+j)%5))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if stmtClosed(stmt) {
+					t.Error("The statement in use must not be closed.")
+				}
+				release()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if c.ll.Len() > 2 {
+		t.Errorf("The cache exceeds its size.got:%d", c.ll.Len())
+	}
+	for el := c.ll.Front(); el != nil; el = el.Next() {
+		if refs := el.Value.(*stmtEntry).refs; refs != 0 {
+			t.Errorf("All the statements must be released.got:%d refs", refs)
+		}
+	}
+	c.close()
+}
+
+func TestStmtCache_Transaction(t *testing.T) {
+	// the only connection is held by the transaction,the statement must be rebound to it or prepared on it.
+	pool, err := OpenPool(&database.Config{Write: &database.DBConfig{DSN: "writer", MaxActive: 1}}, "even_test_script",
+		PoolOptions{Lazy: true, StmtCacheSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	defer resetScript(nil)
+	resetScript(nil)
+
+	if err = insertUser(pool.Master()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = pool.Transaction(ctx, func(tx *Conn) error {
+		for i := 0; i < 2; i++ {
+			if err := insertUser(tx); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Prepared("DELETE FROM users WHERE id = ?", 1).AffectedCount()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("The statements must be executed in the transaction.got:%v", err)
+	}
+	stmts := pool.stmtCaches[pool.writer]
+	if stmts.ll.Len() != 1 || stmts.ll.Front().Value.(*stmtEntry).refs != 0 {
+		t.Error("The cached statement must be released after the transaction.")
+	}
+	if stmtClosed(stmts.ll.Front().Value.(*stmtEntry).stmt) {
+		t.Error("The cached statement must not be closed by the transaction.")
+	}
+}
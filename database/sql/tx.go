@@ -0,0 +1,146 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-10
+ */
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/go-sql-driver/mysql"
+	"strconv"
+	"time"
+)
+
+// isolation levels of transaction
+const (
+	LevelDefault         = sql.LevelDefault
+	LevelReadUncommitted = sql.LevelReadUncommitted
+	LevelReadCommitted   = sql.LevelReadCommitted
+	LevelRepeatableRead  = sql.LevelRepeatableRead
+	LevelSerializable    = sql.LevelSerializable
+)
+
+// TxOptions is the options of transaction.
+// The read only transaction can be started on reader connections.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+}
+
+// mysql error numbers which can be solved by retrying the transaction.
+const (
+	errLockWaitTimeout = 1205
+	errDeadlock        = 1213
+)
+
+// run fn in a transaction on the writer.
+// It commits when fn returns nil,and rolls back when fn returns an error or panics.
+// The panic is re-panicked after rollback.
+func (pool *ConnPool) Transaction(ctx context.Context, fn func(tx *Conn) error) error {
+	return pool.TransactionRetry(ctx, 0, fn)
+}
+
+// run fn in a transaction like Transaction,and retry the whole fn at most retries times
+// on deadlock(1213) and lock wait timeout(1205).
+// The error wrapped by fn is checked by its cause,the wrapper must have Unwrap() or Cause() like github.com/pkg/errors.
+// fn must be safe to run again.
+func (pool *ConnPool) TransactionRetry(ctx context.Context, retries int, fn func(tx *Conn) error) (err error) {
+	backoff := 10 * time.Millisecond
+	for i := 0; ; i++ {
+		err = pool.runTransaction(ctx, fn)
+		if err == nil || i >= retries || !isRetryableTxErr(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (pool *ConnPool) runTransaction(ctx context.Context, fn func(tx *Conn) error) error {
+	return pool.Master().Transaction(ctx, fn)
+}
+
+// run fn in a transaction on the conn like ConnPool.Transaction.
+// It's a savepoint when the conn is already in transaction,so the functions can be composed.
+func (conn *Conn) Transaction(ctx context.Context, fn func(tx *Conn) error) error {
+	return conn.TransactionTx(ctx, nil, fn)
+}
+
+// run fn in a transaction with options.
+func (conn *Conn) TransactionTx(ctx context.Context, opts *TxOptions, fn func(tx *Conn) error) (err error) {
+	if err = conn.BeginTx(ctx, opts); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = conn.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(conn); err != nil {
+		_ = conn.Rollback()
+		return err
+	}
+	return conn.Commit()
+}
+
+// the name of the latest savepoint.
+func (conn *Conn) savepointName() string {
+	return "even_sp_" + strconv.Itoa(len(conn.savepoints))
+}
+
+// create a savepoint for nested transaction.
+func (conn *Conn) savepoint(ctx context.Context, event *Event) error {
+	conn.savepoints = append(conn.savepoints, len(conn.dirtyTables))
+	event.SQL = "SAVEPOINT " + conn.savepointName()
+	if _, err := conn.tx.ExecContext(ctx, event.SQL); err != nil {
+		conn.savepoints = conn.savepoints[:len(conn.savepoints)-1]
+		return contextErr(ctx, err)
+	}
+	return nil
+}
+
+// release the latest savepoint,the writes belong to the outer transaction.
+func (conn *Conn) releaseSavepoint(ctx context.Context, event *Event) error {
+	event.SQL = "RELEASE SAVEPOINT " + conn.savepointName()
+	conn.savepoints = conn.savepoints[:len(conn.savepoints)-1]
+	_, err := conn.tx.ExecContext(ctx, event.SQL)
+	return contextErr(ctx, err)
+}
+
+// roll back to the latest savepoint and discard the tables written after it.
+func (conn *Conn) rollbackSavepoint(ctx context.Context, event *Event) error {
+	event.SQL = "ROLLBACK TO SAVEPOINT " + conn.savepointName()
+	last := len(conn.savepoints) - 1
+	conn.dirtyTables = conn.dirtyTables[:conn.savepoints[last]]
+	conn.savepoints = conn.savepoints[:last]
+	_, err := conn.tx.ExecContext(ctx, event.SQL)
+	return contextErr(ctx, err)
+}
+
+// check whether the transaction can be retried by the error or its causes.
+func isRetryableTxErr(err error) bool {
+	for err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			return mysqlErr.Number == errDeadlock || mysqlErr.Number == errLockWaitTimeout
+		}
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapper.Unwrap()
+		case interface{ Cause() error }:
+			err = wrapper.Cause()
+		default:
+			return false
+		}
+	}
+	return false
+}
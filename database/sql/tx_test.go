@@ -0,0 +1,221 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-10
+ */
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/AbelZhou/even/cache"
+	"github.com/go-sql-driver/mysql"
+	"reflect"
+	"testing"
+)
+
+// wraps the error like github.com/pkg/errors.
+type causeErr struct {
+	cause error
+}
+
+func (e causeErr) Error() string { return "wrapped: " + e.cause.Error() }
+func (e causeErr) Cause() error  { return e.cause }
+
+func insertUser(tx *Conn) error {
+	_, err := tx.Prepared("INSERT INTO users VALUES(?)", 1).AffectedCount()
+	return err
+}
+
+func TestConnPool_Transaction(t *testing.T) {
+	pool := openScriptPool(t, PoolOptions{})
+	defer pool.Close()
+	defer resetScript(nil)
+	ctx := context.Background()
+
+	resetScript(nil)
+	if err := pool.Transaction(ctx, insertUser); err != nil {
+		t.Fatal(err)
+	}
+	if got := recordedStatements(); !reflect.DeepEqual(got, []string{"BEGIN", "INSERT INTO users VALUES(?)", "COMMIT"}) {
+		t.Errorf("The transaction must be committed on success.got:%v", got)
+	}
+
+	resetScript(nil)
+	failed := errors.New("failed")
+	err := pool.Transaction(ctx, func(tx *Conn) error {
+		if err := insertUser(tx); err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Errorf("The error of fn must be returned.got:%v", err)
+	}
+	if got := recordedStatements(); !reflect.DeepEqual(got, []string{"BEGIN", "INSERT INTO users VALUES(?)", "ROLLBACK"}) {
+		t.Errorf("The transaction must be rolled back on error.got:%v", got)
+	}
+
+	resetScript(nil)
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("The panic must be re-panicked.got:%v", p)
+			}
+		}()
+		_ = pool.Transaction(ctx, func(tx *Conn) error {
+			panic("boom")
+		})
+	}()
+	if got := recordedStatements(); !reflect.DeepEqual(got, []string{"BEGIN", "ROLLBACK"}) {
+		t.Errorf("The transaction must be rolled back on panic.got:%v", got)
+	}
+}
+
+func TestConnPool_TransactionRetry(t *testing.T) {
+	pool := openScriptPool(t, PoolOptions{})
+	defer pool.Close()
+	defer resetScript(nil)
+	ctx := context.Background()
+
+	for _, number := range []uint16{errDeadlock, errLockWaitTimeout} {
+		inserts := 0
+		resetScript(func(query string, args []driver.Value) (*scriptResult, error) {
+			if query == "INSERT INTO users VALUES(?)" {
+				if inserts++; inserts == 1 {
+					return nil, &mysql.MySQLError{Number: number}
+				}
+			}
+			return &scriptResult{}, nil
+		})
+		err := pool.TransactionRetry(ctx, 2, func(tx *Conn) error {
+			if err := insertUser(tx); err != nil {
+				return causeErr{err}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("The transaction must be retried on %d.got:%v", number, err)
+		}
+		expect := []string{"BEGIN", "INSERT INTO users VALUES(?)", "ROLLBACK", "BEGIN", "INSERT INTO users VALUES(?)", "COMMIT"}
+		if got := recordedStatements(); !reflect.DeepEqual(got, expect) {
+			t.Errorf("The whole fn must be run again on %d.got:%v", number, got)
+		}
+	}
+
+	attempts := 0
+	resetScript(nil)
+	err := pool.TransactionRetry(ctx, 2, func(tx *Conn) error {
+		attempts++
+		return &mysql.MySQLError{Number: errDeadlock}
+	})
+	if _, ok := err.(*mysql.MySQLError); !ok || attempts != 3 {
+		t.Errorf("The last error must be returned after retries.got:%d attempts,%v", attempts, err)
+	}
+
+	attempts = 0
+	err = pool.TransactionRetry(ctx, 2, func(tx *Conn) error {
+		attempts++
+		return &mysql.MySQLError{Number: 1062}
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("The other errors must not be retried.got:%d attempts,%v", attempts, err)
+	}
+}
+
+func TestConn_Savepoint(t *testing.T) {
+	pool := openScriptPool(t, PoolOptions{})
+	defer pool.Close()
+	gc := cache.NewGCache(100)
+	pool.SetCache(gc, 0)
+	defer resetScript(nil)
+	resetScript(nil)
+
+	exec := func(conn *Conn, sql string) {
+		t.Helper()
+		if _, err := conn.Prepared(sql).AffectedCount(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	step := func(err error, conn *Conn, depth int) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conn.TransactionDepth() != depth {
+			t.Fatalf("The depth of transaction mismatch.expect:%d got:%d", depth, conn.TransactionDepth())
+		}
+	}
+	versions := func() []string { return tableVersions(gc, []string{"a", "b", "c"}) }
+	before := versions()
+
+	conn := pool.Master()
+	step(conn.Begin(), conn, 1)
+	exec(conn, "UPDATE a SET v=1")
+	step(conn.Begin(), conn, 2)
+	exec(conn, "UPDATE b SET v=1")
+	step(conn.Begin(), conn, 3)
+	exec(conn, "UPDATE c SET v=1")
+	step(conn.Commit(), conn, 2)
+	if !reflect.DeepEqual(conn.dirtyTables, []string{"a", "b", "c"}) {
+		t.Errorf("The tables written in the released savepoint belong to the outer transaction.got:%v", conn.dirtyTables)
+	}
+	step(conn.Rollback(), conn, 1)
+	if !reflect.DeepEqual(conn.dirtyTables, []string{"a"}) {
+		t.Errorf("The tables written after the savepoint must be discarded.got:%v", conn.dirtyTables)
+	}
+
+	// the savepoint is not created by the canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := conn.BeginContext(ctx); err != ERR_CANCELED {
+		t.Errorf("The canceled savepoint must return ERR_CANCELED.got:%v", err)
+	}
+	step(nil, conn, 1)
+	step(conn.Commit(), conn, 0)
+
+	expect := []string{"BEGIN", "UPDATE a SET v=1", "SAVEPOINT even_sp_1", "UPDATE b SET v=1", "SAVEPOINT even_sp_2",
+		"UPDATE c SET v=1", "RELEASE SAVEPOINT even_sp_2", "ROLLBACK TO SAVEPOINT even_sp_1", "COMMIT"}
+	if got := recordedStatements(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("The statements mismatch.got:%v", got)
+	}
+	after := versions()
+	if after[0] == before[0] || after[1] != before[1] || after[2] != before[2] {
+		t.Errorf("Only the tables written in the committed transaction must be invalidated.before:%v after:%v", before, after)
+	}
+}
+
+func TestConn_BeginTx(t *testing.T) {
+	pool := openScriptPool(t, PoolOptions{})
+	defer pool.Close()
+	defer resetScript(nil)
+	resetScript(nil)
+	ctx := context.Background()
+
+	reader := pool.Slave()
+	if err := reader.BeginTx(ctx, nil); err != ERR_READERTRANSACTION {
+		t.Errorf("The reader must reject the read write transaction.got:%v", err)
+	}
+	if err := reader.BeginTx(ctx, &TxOptions{Isolation: LevelReadCommitted}); err != ERR_READERTRANSACTION {
+		t.Errorf("The reader must reject the read write transaction.got:%v", err)
+	}
+	if err := reader.BeginSnapshot(ctx); err != nil {
+		t.Fatalf("The reader must accept the read only transaction.got:%v", err)
+	}
+	if err := reader.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	writer := pool.Master()
+	if err := writer.BeginTx(ctx, &TxOptions{Isolation: LevelReadCommitted}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"BEGIN Repeatable Read READ ONLY", "COMMIT", "BEGIN Read Committed", "ROLLBACK"}
+	if got := recordedStatements(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("The options of transaction mismatch.got:%v", got)
+	}
+}
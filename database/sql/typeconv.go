@@ -0,0 +1,263 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-22
+ */
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Converter converts the value scanned from driver to the value in the result map of Fetch.
+// v is never nil,it's int64,uint64,float32,float64,[]byte or time.Time.
+type Converter func(v interface{}, column *sql.ColumnType) (interface{}, error)
+
+var (
+	convertersMu sync.RWMutex
+	converters   = map[string]Converter{
+		"TINYINT":    convertTinyInt,
+		"SMALLINT":   convertSmallInt,
+		"YEAR":       convertSmallInt,
+		"MEDIUMINT":  convertInt,
+		"INT":        convertInt,
+		"BIGINT":     convertBigInt,
+		"FLOAT":      convertFloat32,
+		"DOUBLE":     convertFloat64,
+		"DECIMAL":    convertString,
+		"CHAR":       convertString,
+		"VARCHAR":    convertString,
+		"TINYTEXT":   convertString,
+		"TEXT":       convertString,
+		"MEDIUMTEXT": convertString,
+		"LONGTEXT":   convertString,
+		"ENUM":       convertString,
+		"SET":        convertString,
+		"JSON":       convertJSON,
+		"BIT":        convertBit,
+		"TIME":       convertTime,
+		"DATE":       convertDateTime,
+		"DATETIME":   convertDateTime,
+		"TIMESTAMP":  convertDateTime,
+	}
+)
+
+// override the conversion of a database type name,eg: "DECIMAL","JSON".
+// The types without converter are returned as scanned(BLOB and BINARY are []byte).
+func RegisterConverter(databaseType string, converter Converter) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters[strings.ToUpper(databaseType)] = converter
+}
+
+// convert the scanned value by the database type of column.NULL is always nil.
+func convertValue(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	convertersMu.RLock()
+	converter, ok := converters[column.DatabaseTypeName()]
+	convertersMu.RUnlock()
+	if !ok {
+		return v, nil
+	}
+	return converter(v, column)
+}
+
+// convert DECIMAL to float64,it loses precision.
+// eg: RegisterConverter("DECIMAL", ConvertDecimalFloat64)
+func ConvertDecimalFloat64(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	return convertFloat64(v, column)
+}
+
+// the value of text protocol is []byte and the large unsigned BIGINT is []byte in binary protocol.
+func parseInt(v interface{}) (int64, uint64, bool, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, 0, false, nil
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, n, true, nil
+		}
+		return int64(n), 0, false, nil
+	case []byte:
+		if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+			return i, 0, false, nil
+		}
+		u, err := strconv.ParseUint(string(n), 10, 64)
+		return 0, u, true, err
+	}
+	return 0, 0, false, ERR_UNSUPPORTEDVALUE
+}
+
+// the driver reports the unsigned flag by the scan type of NOT NULL columns only.
+// The nullable column is treated as signed,the value above the signed range is unsigned
+// because only the unsigned column can hold it.
+func isUnsigned(column *sql.ColumnType) bool {
+	if column == nil || column.ScanType() == nil {
+		return false
+	}
+	switch column.ScanType().Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// TINYINT is int8,the unsigned TINYINT is uint8.
+func convertTinyInt(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	i, _, _, err := parseInt(v)
+	if err != nil {
+		return nil, err
+	}
+	if isUnsigned(column) || i > math.MaxInt8 {
+		return uint8(i), nil
+	}
+	return int8(i), nil
+}
+
+// SMALLINT and YEAR are int16,the unsigned ones are uint16.
+func convertSmallInt(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	i, _, _, err := parseInt(v)
+	if err != nil {
+		return nil, err
+	}
+	if isUnsigned(column) || i > math.MaxInt16 {
+		return uint16(i), nil
+	}
+	return int16(i), nil
+}
+
+// MEDIUMINT and INT are int64.
+func convertInt(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	i, _, isUint, err := parseInt(v)
+	if err != nil {
+		return nil, err
+	}
+	if isUint {
+		return nil, ERR_UNSUPPORTEDVALUE
+	}
+	return i, nil
+}
+
+// BIGINT is int64,the unsigned BIGINT is uint64.
+func convertBigInt(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	i, u, isUint, err := parseInt(v)
+	if err != nil {
+		return nil, err
+	}
+	if isUint {
+		return u, nil
+	}
+	if isUnsigned(column) {
+		return uint64(i), nil
+	}
+	return i, nil
+}
+
+func convertFloat32(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	switch f := v.(type) {
+	case float32:
+		return f, nil
+	case float64:
+		return float32(f), nil
+	case []byte:
+		f32, err := strconv.ParseFloat(string(f), 32)
+		return float32(f32), err
+	}
+	return nil, ERR_UNSUPPORTEDVALUE
+}
+
+func convertFloat64(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	switch f := v.(type) {
+	case float32:
+		return float64(f), nil
+	case float64:
+		return f, nil
+	case []byte:
+		return strconv.ParseFloat(string(f), 64)
+	}
+	return nil, ERR_UNSUPPORTEDVALUE
+}
+
+func convertString(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	if b, ok := v.([]byte); ok {
+		return string(b), nil
+	}
+	return v, nil
+}
+
+// JSON is decoded to map[string]interface{},[]interface{},string,float64,bool or nil.
+func convertJSON(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return v, nil
+	}
+	var res interface{}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// BIT is uint64.
+func convertBit(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return v, nil
+	}
+	var res uint64
+	for _, c := range b {
+		res = res<<8 | uint64(c)
+	}
+	return res, nil
+}
+
+// TIME is time.Duration,it ranges from -838:59:59 to 838:59:59.
+func convertTime(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return v, nil
+	}
+	s := string(b)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		return nil, ERR_UNSUPPORTEDVALUE
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return nil, err
+	}
+	d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second))
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
+
+// DATE,DATETIME and TIMESTAMP are time.Time with parseTime=true in DSN,otherwise string.
+func convertDateTime(v interface{}, column *sql.ColumnType) (interface{}, error) {
+	if b, ok := v.([]byte); ok {
+		return string(b), nil
+	}
+	return v, nil
+}
@@ -0,0 +1,91 @@
+/**
+ *  author:Abel
+ *  email:abel.zhou@hotmail.com
+ *  date:2019-07-22
+ */
+package sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConvertValue(t *testing.T) {
+	cases := []struct {
+		conv   Converter
+		value  interface{}
+		expect interface{}
+	}{
+		{convertInt, int64(-1), int64(-1)},
+		{convertInt, []byte("42"), int64(42)},
+		{convertTinyInt, int64(-1), int8(-1)},
+		{convertTinyInt, []byte("200"), uint8(200)},
+		{convertSmallInt, int64(-1), int16(-1)},
+		{convertSmallInt, []byte("65535"), uint16(65535)},
+		{convertBigInt, uint64(math.MaxInt64), int64(math.MaxInt64)},
+		{convertBigInt, []byte("18446744073709551615"), uint64(math.MaxUint64)},
+		{convertFloat32, []byte("1.5"), float32(1.5)},
+		{convertFloat64, float32(2.5), float64(2.5)},
+		{convertString, []byte("12.30"), "12.30"},
+		{ConvertDecimalFloat64, []byte("12.30"), 12.3},
+		{convertJSON, []byte(`{"a":[1,"b"]}`), map[string]interface{}{"a": []interface{}{float64(1), "b"}}},
+		{convertBit, []byte{0x01, 0x02}, uint64(258)},
+		{convertTime, []byte("-01:02:03.5"), -(time.Hour + 2*time.Minute + 3500*time.Millisecond)},
+		{convertDateTime, []byte("2019-07-22"), "2019-07-22"},
+	}
+	for i, c := range cases {
+		got, err := c.conv(c.value, nil)
+		if err != nil {
+			t.Fatalf("case %d failed:%v", i, err)
+		}
+		if !reflect.DeepEqual(got, c.expect) {
+			t.Errorf("case %d mismatch.got:%#v expect:%#v", i, got, c.expect)
+		}
+	}
+
+	if _, err := convertInt([]byte("abc"), nil); err == nil {
+		t.Error("The invalid integer must return an error.")
+	}
+	if v, err := convertValue(nil, nil); v != nil || err != nil {
+		t.Errorf("NULL must be nil.got:%v %v", v, err)
+	}
+}
+
+func TestScanMap_Integers(t *testing.T) {
+	pool := openScriptPool(t, PoolOptions{})
+	defer pool.Close()
+	defer resetScript(nil)
+	nullable := reflect.TypeOf(sql.NullInt64{})
+	resetScript(func(query string, args []driver.Value) (*scriptResult, error) {
+		return &scriptResult{
+			columns:   []string{"tiny", "tiny_unsigned", "small", "small_unsigned", "big", "big_unsigned"},
+			types:     []string{"TINYINT", "TINYINT", "SMALLINT", "SMALLINT", "BIGINT", "BIGINT"},
+			scanTypes: []reflect.Type{nullable, reflect.TypeOf(uint8(0)), nullable, reflect.TypeOf(uint16(0)), nullable, reflect.TypeOf(uint64(0))},
+			rows: [][]driver.Value{
+				{int64(-1), int64(1), int64(-1), int64(1), int64(-1), int64(1)},
+				{nil, int64(200), int64(300), int64(40000), []byte("18446744073709551615"), []byte("18446744073709551615")},
+				// the nullable unsigned columns
+				{int64(200), int64(0), int64(40000), int64(0), int64(1), int64(0)},
+			},
+		}, nil
+	})
+
+	res, err := pool.Master().Prepared("SELECT * FROM numbers").FetchAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []map[string]interface{}{
+		{"tiny": int8(-1), "tiny_unsigned": uint8(1), "small": int16(-1), "small_unsigned": uint16(1), "big": int64(-1), "big_unsigned": uint64(1)},
+		{"tiny": nil, "tiny_unsigned": uint8(200), "small": int16(300), "small_unsigned": uint16(40000), "big": uint64(math.MaxUint64), "big_unsigned": uint64(math.MaxUint64)},
+		{"tiny": uint8(200), "tiny_unsigned": uint8(0), "small": uint16(40000), "small_unsigned": uint16(0), "big": int64(1), "big_unsigned": uint64(0)},
+	}
+	for i := range expect {
+		if i >= len(res) || !reflect.DeepEqual(res[i], expect[i]) {
+			t.Errorf("row %d mismatch.got:%#v", i, res)
+		}
+	}
+}
@@ -9,11 +9,17 @@ import (
 	"fmt"
 	"github.com/AbelZhou/even/database"
 	"strconv"
+	"strings"
 )
 
 type ConfigDriver interface {
 	Open()
 	Read(key string) string
+	// read the keys with prefix,it returns key -> value.
+	ReadPrefix(prefix string) map[string]string
+	// watch the keys with prefix until stop is called.
+	// callback is called with the new value,the value is "" when the key is deleted.
+	Watch(prefix string, callback func(key, value string)) (stop func(), err error)
 	Close()
 }
 
@@ -37,20 +43,21 @@ func CreateConf(driver ConfigDriver) *Conf {
 // dbconf/(dbtag)/write/MaxIdle 5
 // dbconf/(dbtag)/write/IdleTimeout 1000
 // dbconf/(dbtag)/read0/DSN "abel:123456@tcp(127.0.0.1:3307)/test?charset=utf8mb4&parseTime=true&loc=Local"
+// dbconf/(dbtag)/read0/Weight 2
 // dbconf/(dbtag)/read1/DSN "abel:123456@tcp(127.0.0.1:3308)/test?charset=utf8mb4&parseTime=true&loc=Local"
 // dbconf/(dbtag)/read2/DSN "abel:123456@tcp(127.0.0.1:3309)/test?charset=utf8mb4&parseTime=true&loc=Local"
 //
-// TOML:
-//	[account]
+// TOML of FileDriver:
+//	[dbconf.account]
 //    DefMaxActive = 20
 //    DefMaxIdle = 10
 //    DefIdleTimeout = 200
-//    [write]
-//        dsn = "abel:123456@tcp(127.0.0.1:3306)/test?charset=utf8mb4&parseTime=true&loc=Local"
-//    [[read]]
-//        dns = "abel:123456@tcp(127.0.0.1:3306)/test?charset=utf8mb4&parseTime=true&loc=Local"
-//    [[read]]
-//        dns = "abel:123456@tcp(127.0.0.1:3306)/test?charset=utf8mb4&parseTime=true&loc=Local"
+//    [dbconf.account.write]
+//        DSN = "abel:123456@tcp(127.0.0.1:3306)/test?charset=utf8mb4&parseTime=true&loc=Local"
+//    [[dbconf.account.read]]
+//        DSN = "abel:123456@tcp(127.0.0.1:3306)/test?charset=utf8mb4&parseTime=true&loc=Local"
+//    [[dbconf.account.read]]
+//        DSN = "abel:123456@tcp(127.0.0.1:3306)/test?charset=utf8mb4&parseTime=true&loc=Local"
 func (c *Conf) GetDBConf(dbtag string) *database.Config {
 	c.driver.Open()
 	defer c.driver.Close()
@@ -92,11 +99,13 @@ func (c *Conf) GetDBConf(dbtag string) *database.Config {
 		readMaxActive, _ := strconv.Atoi(c.driver.Read(prefix + "/" + readerName + "/MaxActive"))
 		readMaxIdle, _ := strconv.Atoi(c.driver.Read(prefix + "/" + readerName + "/MaxIdle"))
 		readIdleTimeout, _ := strconv.Atoi(c.driver.Read(prefix + "/" + readerName + "/IdleTimeout"))
+		readWeight, _ := strconv.Atoi(c.driver.Read(prefix + "/" + readerName + "/Weight"))
 		readers = append(readers, &database.DBConfig{
 			DSN:         readDsn,
 			MaxIdle:     readMaxIdle,
 			MaxActive:   readMaxActive,
 			IdleTimeout: readIdleTimeout,
+			Weight:      readWeight,
 		})
 		idx++
 	}
@@ -116,6 +125,38 @@ func (c *Conf) GetDBConf(dbtag string) *database.Config {
 	return dbConf
 }
 
+// read the keys with prefix.
+func (c *Conf) ReadPrefix(prefix string) map[string]string {
+	c.driver.Open()
+	defer c.driver.Close()
+	return c.driver.ReadPrefix(prefix)
+}
+
+// watch the keys with prefix until stop is called.
+// callback is called with the new value,the value is "" when the key is deleted.
+func (c *Conf) Watch(prefix string, callback func(key, value string)) (stop func(), err error) {
+	return c.driver.Watch(prefix, callback)
+}
+
+// watch the changes of db config,callback is called with the dbtag of changed key.
+// eg: the change of /dbconf/account/read0/DSN calls callback("account")
+func (c *Conf) WatchDBConf(callback func(dbtag string)) (stop func(), err error) {
+	return c.driver.Watch("/dbconf/", func(key, value string) {
+		if dbtag := dbTag(key); dbtag != "" {
+			callback(dbtag)
+		}
+	})
+}
+
+// get the dbtag of key /dbconf/(dbtag)/...
+func dbTag(key string) string {
+	parts := strings.SplitN(strings.TrimPrefix(key, "/dbconf/"), "/", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
 // get memcache config
 // example:
 // /cacheconf/account/memcache0/DSN "127.0.0.1:11211"
@@ -124,7 +165,7 @@ func (c *Conf) GetMemcacheConf(cacheTag string) []string {
 
 	idx := 0
 	for {
-		key := fmt.Sprintf("/cacheconf/%s/memcache%d/DSN", cacheTag)
+		key := fmt.Sprintf("/cacheconf/%s/memcache%d/DSN", cacheTag, idx)
 		memcacheDSN := c.driver.Read(key)
 		if memcacheDSN == "" {
 			break
@@ -7,6 +7,7 @@ package conf
 
 import (
 	"context"
+	"github.com/coreos/etcd/mvcc/mvccpb"
 	"go.etcd.io/etcd/clientv3"
 	"time"
 )
@@ -23,12 +24,7 @@ type EtcdDriver struct {
 
 //open conn
 func (ed *EtcdDriver) Open() {
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   ed.Endpoints,
-		DialTimeout: time.Duration(ed.DialTimeout) * time.Second,
-		Username:    ed.Username,
-		Password:    ed.Password,
-	})
+	cli, err := ed.newClient()
 	if err != nil {
 		panic(err)
 	}
@@ -52,3 +48,153 @@ func (ed *EtcdDriver) Read(key string) string {
 func (ed *EtcdDriver) Close() {
 	_ = ed.client.Close()
 }
+
+// read the keys with prefix.
+func (ed *EtcdDriver) ReadPrefix(prefix string) map[string]string {
+	response, err := ed.client.Get(context.TODO(), prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil
+	}
+	return kvsMap(response.Kvs)
+}
+
+// watch the keys with prefix,callback is called with the new value,the value is "" when deleted.
+// The watch is resumed from the last revision after reconnected,
+// and the keys are resynced when the revision was compacted.
+// It uses a dedicated client which is closed by stop.
+func (ed *EtcdDriver) Watch(prefix string, callback func(key, value string)) (stop func(), err error) {
+	cli, err := ed.newClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	response, err := cli.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		cancel()
+		_ = cli.Close()
+		return nil, err
+	}
+
+	w := &etcdWatch{
+		cli:      cli,
+		prefix:   prefix,
+		callback: callback,
+		revision: response.Header.Revision,
+		snapshot: kvsMap(response.Kvs),
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+		_ = cli.Close()
+	}, nil
+}
+
+func (ed *EtcdDriver) newClient() (*clientv3.Client, error) {
+	return clientv3.New(clientv3.Config{
+		Endpoints:   ed.Endpoints,
+		DialTimeout: time.Duration(ed.DialTimeout) * time.Second,
+		Username:    ed.Username,
+		Password:    ed.Password,
+	})
+}
+
+// the watch of prefix with the last revision and values.
+type etcdWatch struct {
+	cli      *clientv3.Client
+	prefix   string
+	callback func(key, value string)
+	revision int64
+	snapshot map[string]string
+}
+
+// watch until ctx is canceled,it reconnects with backoff when the watch is broken.
+func (w *etcdWatch) run(ctx context.Context) {
+	backoff := 100 * time.Millisecond
+	for {
+		compacted := w.watch(ctx, &backoff)
+		if compacted {
+			if err := w.resync(ctx); err == nil {
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > 5*time.Second {
+			backoff = 5 * time.Second
+		}
+	}
+}
+
+// watch from the next revision until the watch is broken.
+// It returns true when the revision was compacted.
+func (w *etcdWatch) watch(ctx context.Context, backoff *time.Duration) bool {
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	watchChan := w.cli.Watch(watchCtx, w.prefix, clientv3.WithPrefix(), clientv3.WithRev(w.revision+1))
+	for response := range watchChan {
+		if response.CompactRevision != 0 {
+			return true
+		}
+		if response.Err() != nil {
+			return false
+		}
+		*backoff = 100 * time.Millisecond
+		for _, event := range response.Events {
+			key, value := string(event.Kv.Key), string(event.Kv.Value)
+			if event.Type == clientv3.EventTypeDelete {
+				value = ""
+				delete(w.snapshot, key)
+			} else {
+				w.snapshot[key] = value
+			}
+			w.callback(key, value)
+		}
+		if response.Header.Revision > w.revision {
+			w.revision = response.Header.Revision
+		}
+	}
+	return false
+}
+
+// read all the keys and notify the changes since the snapshot.
+func (w *etcdWatch) resync(ctx context.Context) error {
+	response, err := w.cli.Get(ctx, w.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	current := kvsMap(response.Kvs)
+	diffSnapshot(w.snapshot, current, w.callback)
+	w.snapshot = current
+	w.revision = response.Header.Revision
+	return nil
+}
+
+// call callback with the changed keys,the value of deleted key is "".
+func diffSnapshot(old, current map[string]string, callback func(key, value string)) {
+	for key, value := range current {
+		if oldValue, ok := old[key]; !ok || oldValue != value {
+			callback(key, value)
+		}
+	}
+	for key := range old {
+		if _, ok := current[key]; !ok {
+			callback(key, "")
+		}
+	}
+}
+
+func kvsMap(kvs []*mvccpb.KeyValue) map[string]string {
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		m[string(kv.Key)] = string(kv.Value)
+	}
+	return m
+}
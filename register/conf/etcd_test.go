@@ -14,3 +14,21 @@ func TestConf_GetDBConf(t *testing.T) {
 		t.Error("Get database config failed!")
 	}
 }
+
+func TestDiffSnapshot(t *testing.T) {
+	old := map[string]string{"/dbconf/account/write/DSN": "a", "/dbconf/account/read0/DSN": "b"}
+	current := map[string]string{"/dbconf/account/write/DSN": "c", "/dbconf/order/write/DSN": "d"}
+	changes := make(map[string]string)
+	diffSnapshot(old, current, func(key, value string) {
+		changes[key] = value
+	})
+	if len(changes) != 3 || changes["/dbconf/account/write/DSN"] != "c" || changes["/dbconf/order/write/DSN"] != "d" {
+		t.Errorf("The changed keys mismatch.got:%v", changes)
+	}
+	if value, ok := changes["/dbconf/account/read0/DSN"]; !ok || value != "" {
+		t.Error("The deleted key must be notified with empty value.")
+	}
+	if dbTag("/dbconf/account/read0/DSN") != "account" || dbTag("/dbconf/account") != "" {
+		t.Error("The dbtag of key mismatch.")
+	}
+}
@@ -0,0 +1,201 @@
+/*
+   author:Abel
+   email:abel.zhou@hotmail.com
+   date:2019-08-01
+*/
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the changes of file in fileReloadDelay are reloaded once.
+const fileReloadDelay = 100 * time.Millisecond
+
+// FileDriver reads the config from a TOML,YAML or JSON file.
+// The nested tables are mapped to the keys like etcd and the arrays are indexed from 0,
+// eg: the TOML
+//	[dbconf.account]
+//	DefMaxActive = 20
+//	[dbconf.account.write]
+//	DSN = "abel:123456@tcp(127.0.0.1:3306)/test"
+//	[[dbconf.account.read]]
+//	DSN = "abel:123456@tcp(127.0.0.1:3307)/test"
+// is mapped to /dbconf/account/DefMaxActive,/dbconf/account/write/DSN and /dbconf/account/read0/DSN.
+// The keys are case insensitive.
+type FileDriver struct {
+	Path   string
+	Format string //toml,yaml or json.Default by the extension of Path
+	mu     sync.RWMutex
+	values map[string]fileValue //lower case key -> value
+}
+
+type fileValue struct {
+	key   string
+	value string
+}
+
+// load the file.It panics when the file can not be loaded like EtcdDriver.
+func (fd *FileDriver) Open() {
+	values, err := fd.load()
+	if err != nil {
+		panic(err)
+	}
+	fd.mu.Lock()
+	fd.values = values
+	fd.mu.Unlock()
+}
+
+//read conf
+func (fd *FileDriver) Read(key string) string {
+	fd.mu.RLock()
+	defer fd.mu.RUnlock()
+	return fd.values[strings.ToLower(key)].value
+}
+
+// read the keys with prefix.
+func (fd *FileDriver) ReadPrefix(prefix string) map[string]string {
+	fd.mu.RLock()
+	defer fd.mu.RUnlock()
+	return filterPrefix(fd.values, prefix)
+}
+
+// watch the keys with prefix,the file is reloaded when it's changed.
+// The changes are ignored until the file can be parsed,eg: it's being edited.
+func (fd *FileDriver) Watch(prefix string, callback func(key, value string)) (stop func(), err error) {
+	values, err := fd.load()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := filterPrefix(values, prefix)
+
+	// watch the directory because the file may be replaced by editors.
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err = watcher.Add(filepath.Dir(fd.Path)); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		var reload <-chan time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == filepath.Clean(fd.Path) {
+					reload = time.After(fileReloadDelay)
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			case <-reload:
+				reload = nil
+				values, err := fd.load()
+				if err != nil {
+					continue
+				}
+				current := filterPrefix(values, prefix)
+				diffSnapshot(snapshot, current, callback)
+				snapshot = current
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		_ = watcher.Close()
+	}, nil
+}
+
+//close resource.
+func (fd *FileDriver) Close() {
+}
+
+// parse the file to the keys.
+func (fd *FileDriver) load() (map[string]fileValue, error) {
+	data, err := ioutil.ReadFile(fd.Path)
+	if err != nil {
+		return nil, err
+	}
+	format := fd.Format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(fd.Path), ".")
+	}
+
+	var root interface{}
+	switch strings.ToLower(format) {
+	case "toml":
+		m := make(map[string]interface{})
+		_, err = toml.Decode(string(data), &m)
+		root = m
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &root)
+	case "json":
+		err = json.Unmarshal(data, &root)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]fileValue)
+	flatten("", reflect.ValueOf(root), values)
+	return values, nil
+}
+
+// flatten the nested maps and arrays to the keys.
+func flatten(key string, v reflect.Value, values map[string]fileValue) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			flatten(key+"/"+fmt.Sprint(k.Interface()), v.MapIndex(k), values)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			flatten(key+strconv.Itoa(i), v.Index(i), values)
+		}
+	case reflect.Float32, reflect.Float64:
+		values[strings.ToLower(key)] = fileValue{key, strconv.FormatFloat(v.Float(), 'f', -1, 64)}
+	default:
+		values[strings.ToLower(key)] = fileValue{key, fmt.Sprint(v.Interface())}
+	}
+}
+
+// get the keys with prefix,the prefix is case insensitive.
+func filterPrefix(values map[string]fileValue, prefix string) map[string]string {
+	prefix = strings.ToLower(prefix)
+	m := make(map[string]string)
+	for lower, v := range values {
+		if strings.HasPrefix(lower, prefix) {
+			m[v.key] = v.value
+		}
+	}
+	return m
+}
@@ -0,0 +1,100 @@
+/*
+   author:Abel
+   email:abel.zhou@hotmail.com
+   date:2019-08-01
+*/
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var fileConfs = map[string]string{
+	"conf.toml": `
+[dbconf.account]
+DefMaxActive = 20
+[dbconf.account.write]
+dsn = "abel:123456@tcp(127.0.0.1:3306)/test"
+[[dbconf.account.read]]
+DSN = "abel:123456@tcp(127.0.0.1:3307)/test"
+Weight = 2
+[[dbconf.account.read]]
+DSN = "abel:123456@tcp(127.0.0.1:3308)/test"
+`,
+	"conf.yaml": `
+dbconf:
+  account:
+    DefMaxActive: 20
+    write:
+      DSN: abel:123456@tcp(127.0.0.1:3306)/test
+    read:
+      - DSN: abel:123456@tcp(127.0.0.1:3307)/test
+        Weight: 2
+      - DSN: abel:123456@tcp(127.0.0.1:3308)/test
+`,
+	"conf.json": `{"dbconf": {"account": {
+	"DefMaxActive": 20,
+	"write": {"DSN": "abel:123456@tcp(127.0.0.1:3306)/test"},
+	"read": [{"DSN": "abel:123456@tcp(127.0.0.1:3307)/test", "Weight": 2}, {"DSN": "abel:123456@tcp(127.0.0.1:3308)/test"}]
+}}}`,
+}
+
+func TestFileDriver_GetDBConf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range fileConfs {
+		path := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dbConfig := CreateConf(&FileDriver{Path: path}).GetDBConf("account")
+		if dbConfig.DefMaxActive != 20 || dbConfig.Write.DSN != "abel:123456@tcp(127.0.0.1:3306)/test" {
+			t.Errorf("%s: the db config mismatch.got:%+v", name, dbConfig)
+		}
+		if len(dbConfig.Read) != 2 || dbConfig.Read[0].Weight != 2 || dbConfig.Read[1].DSN != "abel:123456@tcp(127.0.0.1:3308)/test" {
+			t.Errorf("%s: the readers mismatch.got:%+v", name, dbConfig.Read)
+		}
+	}
+}
+
+func TestFileDriver_Watch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "conf.json")
+	if err = ioutil.WriteFile(path, []byte(fileConfs["conf.json"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan string, 10)
+	stop, err := CreateConf(&FileDriver{Path: path}).WatchDBConf(func(dbtag string) {
+		changes <- dbtag
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	content := `{"dbconf": {"account": {"write": {"DSN": "abel:123456@tcp(127.0.0.1:3309)/test"}}}}`
+	if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case dbtag := <-changes:
+		if dbtag != "account" {
+			t.Errorf("The changed dbtag mismatch.got:%s", dbtag)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("The change of file must be notified.")
+	}
+}